@@ -0,0 +1,98 @@
+// Package admin is a gRPC service managing the levels from which yawhg writes logs, see admin.proto
+package admin
+
+//go:generate protoc --go_out=plugins=grpc,paths=source_relative:. admin.proto
+
+import (
+	"context"
+	"sort"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/MarcvanMelle/yawhg"
+)
+
+// Register registers the LevelAdmin service on the server
+func Register(s *grpc.Server) {
+	RegisterLevelAdminServer(s, levelAdmin{})
+}
+
+type levelAdmin struct{}
+
+// GetLevels returns the global level and the levels set for components
+func (levelAdmin) GetLevels(ctx context.Context, req *GetLevelsRequest) (*Levels, error) {
+	return currentLevels(), nil
+}
+
+// SetLevel sets the global level, or the level of a component. An empty level clears the level of a component
+func (levelAdmin) SetLevel(ctx context.Context, req *SetLevelRequest) (*Levels, error) {
+	if req.Component != "" && req.Level == "" {
+		yawhg.ClearComponentLevel(req.Component)
+		return currentLevels(), nil
+	}
+
+	level, err := yawhg.ParseLevel(req.Level)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if req.Component == "" {
+		yawhg.SetLevel(level)
+	} else {
+		yawhg.SetComponentLevel(req.Component, level)
+	}
+
+	return currentLevels(), nil
+}
+
+// ListLoggers returns the names of the named loggers created so far
+func (levelAdmin) ListLoggers(ctx context.Context, req *ListLoggersRequest) (*ListLoggersResponse, error) {
+	return &ListLoggersResponse{Loggers: yawhg.Loggers()}, nil
+}
+
+// WatchLevels streams every later change of the global and component levels, until the client cancels the stream.
+// The headers are sent once the stream is subscribed to the changes
+func (levelAdmin) WatchLevels(req *WatchLevelsRequest, stream LevelAdmin_WatchLevelsServer) error {
+	changes, unsubscribe := yawhg.SubscribeLevelChanges()
+	defer unsubscribe()
+
+	// the headers tell the client that the changes from now on are streamed
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case change := <-changes:
+			if err := stream.Send(levelChange(change)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func currentLevels() *Levels {
+	levels := &Levels{Level: yawhg.GetLevel().String()}
+	for name, level := range yawhg.ComponentLevels() {
+		levels.Components = append(levels.Components, &ComponentLevel{Component: name, Level: level.String()})
+	}
+	sort.Slice(levels.Components, func(i, j int) bool {
+		return levels.Components[i].Component < levels.Components[j].Component
+	})
+
+	return levels
+}
+
+func levelChange(change yawhg.LevelChange) *LevelChange {
+	var expires int64
+	if !change.Expires.IsZero() {
+		expires = change.Expires.Unix()
+	}
+
+	return &LevelChange{Component: change.Component, Level: change.Level, ExpiresUnix: expires}
+}
@@ -0,0 +1,622 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: admin.proto
+
+package admin
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type GetLevelsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetLevelsRequest) Reset()         { *m = GetLevelsRequest{} }
+func (m *GetLevelsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetLevelsRequest) ProtoMessage()    {}
+func (*GetLevelsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{0}
+}
+
+func (m *GetLevelsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetLevelsRequest.Unmarshal(m, b)
+}
+func (m *GetLevelsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetLevelsRequest.Marshal(b, m, deterministic)
+}
+func (m *GetLevelsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetLevelsRequest.Merge(m, src)
+}
+func (m *GetLevelsRequest) XXX_Size() int {
+	return xxx_messageInfo_GetLevelsRequest.Size(m)
+}
+func (m *GetLevelsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetLevelsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetLevelsRequest proto.InternalMessageInfo
+
+type SetLevelRequest struct {
+	// component is empty for the global level
+	Component string `protobuf:"bytes,1,opt,name=component,proto3" json:"component,omitempty"`
+	// level is one of "debug", "info" or "error"
+	Level                string   `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetLevelRequest) Reset()         { *m = SetLevelRequest{} }
+func (m *SetLevelRequest) String() string { return proto.CompactTextString(m) }
+func (*SetLevelRequest) ProtoMessage()    {}
+func (*SetLevelRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{1}
+}
+
+func (m *SetLevelRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetLevelRequest.Unmarshal(m, b)
+}
+func (m *SetLevelRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetLevelRequest.Marshal(b, m, deterministic)
+}
+func (m *SetLevelRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetLevelRequest.Merge(m, src)
+}
+func (m *SetLevelRequest) XXX_Size() int {
+	return xxx_messageInfo_SetLevelRequest.Size(m)
+}
+func (m *SetLevelRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetLevelRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetLevelRequest proto.InternalMessageInfo
+
+func (m *SetLevelRequest) GetComponent() string {
+	if m != nil {
+		return m.Component
+	}
+	return ""
+}
+
+func (m *SetLevelRequest) GetLevel() string {
+	if m != nil {
+		return m.Level
+	}
+	return ""
+}
+
+type ComponentLevel struct {
+	Component            string   `protobuf:"bytes,1,opt,name=component,proto3" json:"component,omitempty"`
+	Level                string   `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ComponentLevel) Reset()         { *m = ComponentLevel{} }
+func (m *ComponentLevel) String() string { return proto.CompactTextString(m) }
+func (*ComponentLevel) ProtoMessage()    {}
+func (*ComponentLevel) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{2}
+}
+
+func (m *ComponentLevel) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ComponentLevel.Unmarshal(m, b)
+}
+func (m *ComponentLevel) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ComponentLevel.Marshal(b, m, deterministic)
+}
+func (m *ComponentLevel) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ComponentLevel.Merge(m, src)
+}
+func (m *ComponentLevel) XXX_Size() int {
+	return xxx_messageInfo_ComponentLevel.Size(m)
+}
+func (m *ComponentLevel) XXX_DiscardUnknown() {
+	xxx_messageInfo_ComponentLevel.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ComponentLevel proto.InternalMessageInfo
+
+func (m *ComponentLevel) GetComponent() string {
+	if m != nil {
+		return m.Component
+	}
+	return ""
+}
+
+func (m *ComponentLevel) GetLevel() string {
+	if m != nil {
+		return m.Level
+	}
+	return ""
+}
+
+type Levels struct {
+	Level                string            `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"`
+	Components           []*ComponentLevel `protobuf:"bytes,2,rep,name=components,proto3" json:"components,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Levels) Reset()         { *m = Levels{} }
+func (m *Levels) String() string { return proto.CompactTextString(m) }
+func (*Levels) ProtoMessage()    {}
+func (*Levels) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{3}
+}
+
+func (m *Levels) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Levels.Unmarshal(m, b)
+}
+func (m *Levels) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Levels.Marshal(b, m, deterministic)
+}
+func (m *Levels) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Levels.Merge(m, src)
+}
+func (m *Levels) XXX_Size() int {
+	return xxx_messageInfo_Levels.Size(m)
+}
+func (m *Levels) XXX_DiscardUnknown() {
+	xxx_messageInfo_Levels.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Levels proto.InternalMessageInfo
+
+func (m *Levels) GetLevel() string {
+	if m != nil {
+		return m.Level
+	}
+	return ""
+}
+
+func (m *Levels) GetComponents() []*ComponentLevel {
+	if m != nil {
+		return m.Components
+	}
+	return nil
+}
+
+type ListLoggersRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListLoggersRequest) Reset()         { *m = ListLoggersRequest{} }
+func (m *ListLoggersRequest) String() string { return proto.CompactTextString(m) }
+func (*ListLoggersRequest) ProtoMessage()    {}
+func (*ListLoggersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{4}
+}
+
+func (m *ListLoggersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListLoggersRequest.Unmarshal(m, b)
+}
+func (m *ListLoggersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListLoggersRequest.Marshal(b, m, deterministic)
+}
+func (m *ListLoggersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListLoggersRequest.Merge(m, src)
+}
+func (m *ListLoggersRequest) XXX_Size() int {
+	return xxx_messageInfo_ListLoggersRequest.Size(m)
+}
+func (m *ListLoggersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListLoggersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListLoggersRequest proto.InternalMessageInfo
+
+type ListLoggersResponse struct {
+	Loggers              []string `protobuf:"bytes,1,rep,name=loggers,proto3" json:"loggers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListLoggersResponse) Reset()         { *m = ListLoggersResponse{} }
+func (m *ListLoggersResponse) String() string { return proto.CompactTextString(m) }
+func (*ListLoggersResponse) ProtoMessage()    {}
+func (*ListLoggersResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{5}
+}
+
+func (m *ListLoggersResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListLoggersResponse.Unmarshal(m, b)
+}
+func (m *ListLoggersResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListLoggersResponse.Marshal(b, m, deterministic)
+}
+func (m *ListLoggersResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListLoggersResponse.Merge(m, src)
+}
+func (m *ListLoggersResponse) XXX_Size() int {
+	return xxx_messageInfo_ListLoggersResponse.Size(m)
+}
+func (m *ListLoggersResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListLoggersResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListLoggersResponse proto.InternalMessageInfo
+
+func (m *ListLoggersResponse) GetLoggers() []string {
+	if m != nil {
+		return m.Loggers
+	}
+	return nil
+}
+
+type WatchLevelsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchLevelsRequest) Reset()         { *m = WatchLevelsRequest{} }
+func (m *WatchLevelsRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchLevelsRequest) ProtoMessage()    {}
+func (*WatchLevelsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{6}
+}
+
+func (m *WatchLevelsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchLevelsRequest.Unmarshal(m, b)
+}
+func (m *WatchLevelsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchLevelsRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchLevelsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchLevelsRequest.Merge(m, src)
+}
+func (m *WatchLevelsRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchLevelsRequest.Size(m)
+}
+func (m *WatchLevelsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchLevelsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchLevelsRequest proto.InternalMessageInfo
+
+type LevelChange struct {
+	// component is empty for the global level
+	Component string `protobuf:"bytes,1,opt,name=component,proto3" json:"component,omitempty"`
+	// level is empty when the level of a component is cleared
+	Level string `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"`
+	// expires_unix is the expiry of a temporary global level in seconds since the epoch, or 0
+	ExpiresUnix          int64    `protobuf:"varint,3,opt,name=expires_unix,json=expiresUnix,proto3" json:"expires_unix,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LevelChange) Reset()         { *m = LevelChange{} }
+func (m *LevelChange) String() string { return proto.CompactTextString(m) }
+func (*LevelChange) ProtoMessage()    {}
+func (*LevelChange) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73a7fc70dcc2027c, []int{7}
+}
+
+func (m *LevelChange) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LevelChange.Unmarshal(m, b)
+}
+func (m *LevelChange) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LevelChange.Marshal(b, m, deterministic)
+}
+func (m *LevelChange) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LevelChange.Merge(m, src)
+}
+func (m *LevelChange) XXX_Size() int {
+	return xxx_messageInfo_LevelChange.Size(m)
+}
+func (m *LevelChange) XXX_DiscardUnknown() {
+	xxx_messageInfo_LevelChange.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LevelChange proto.InternalMessageInfo
+
+func (m *LevelChange) GetComponent() string {
+	if m != nil {
+		return m.Component
+	}
+	return ""
+}
+
+func (m *LevelChange) GetLevel() string {
+	if m != nil {
+		return m.Level
+	}
+	return ""
+}
+
+func (m *LevelChange) GetExpiresUnix() int64 {
+	if m != nil {
+		return m.ExpiresUnix
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*GetLevelsRequest)(nil), "yawhg.admin.GetLevelsRequest")
+	proto.RegisterType((*SetLevelRequest)(nil), "yawhg.admin.SetLevelRequest")
+	proto.RegisterType((*ComponentLevel)(nil), "yawhg.admin.ComponentLevel")
+	proto.RegisterType((*Levels)(nil), "yawhg.admin.Levels")
+	proto.RegisterType((*ListLoggersRequest)(nil), "yawhg.admin.ListLoggersRequest")
+	proto.RegisterType((*ListLoggersResponse)(nil), "yawhg.admin.ListLoggersResponse")
+	proto.RegisterType((*WatchLevelsRequest)(nil), "yawhg.admin.WatchLevelsRequest")
+	proto.RegisterType((*LevelChange)(nil), "yawhg.admin.LevelChange")
+}
+
+func init() { proto.RegisterFile("admin.proto", fileDescriptor_73a7fc70dcc2027c) }
+
+var fileDescriptor_73a7fc70dcc2027c = []byte{
+	// 357 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x53, 0xc1, 0x4a, 0xc3, 0x40,
+	0x10, 0x25, 0x0d, 0x56, 0x33, 0x11, 0x95, 0x6d, 0x0f, 0x4b, 0xad, 0x18, 0x23, 0x42, 0x4e, 0x89,
+	0xd4, 0xa3, 0x88, 0x68, 0x15, 0x41, 0x5a, 0x90, 0x88, 0x08, 0x7a, 0x90, 0x34, 0x5d, 0x92, 0x40,
+	0xba, 0x1b, 0xb3, 0xdb, 0x5a, 0x7f, 0xc9, 0xaf, 0x94, 0x6e, 0xd2, 0x36, 0xdb, 0xea, 0xa5, 0xc7,
+	0x79, 0xf3, 0xe6, 0x65, 0xe6, 0xbd, 0x2c, 0x98, 0xc1, 0x70, 0x94, 0x50, 0x37, 0xcb, 0x99, 0x60,
+	0xc8, 0xfc, 0x0e, 0xbe, 0xe2, 0xc8, 0x95, 0x90, 0x8d, 0xe0, 0xe0, 0x81, 0x88, 0x1e, 0x99, 0x90,
+	0x94, 0xfb, 0xe4, 0x73, 0x4c, 0xb8, 0xb0, 0xef, 0x61, 0xff, 0xb9, 0xc4, 0x4a, 0x08, 0xb5, 0xc1,
+	0x08, 0xd9, 0x28, 0x63, 0x94, 0x50, 0x81, 0x35, 0x4b, 0x73, 0x0c, 0x7f, 0x09, 0xa0, 0x26, 0x6c,
+	0xa5, 0x33, 0x36, 0xae, 0xc9, 0x4e, 0x51, 0xd8, 0x77, 0xb0, 0xd7, 0x9d, 0x53, 0xa4, 0xd8, 0x46,
+	0x2a, 0xef, 0x50, 0x2f, 0xb6, 0x5b, 0xf6, 0xb5, 0x4a, 0x1f, 0x5d, 0x02, 0x2c, 0x24, 0x38, 0xae,
+	0x59, 0xba, 0x63, 0x76, 0x0e, 0xdd, 0xca, 0x89, 0xae, 0xba, 0x84, 0x5f, 0xa1, 0xdb, 0x4d, 0x40,
+	0xbd, 0x84, 0x8b, 0x1e, 0x8b, 0x22, 0x92, 0x2f, 0xee, 0xf7, 0xa0, 0xa1, 0xa0, 0x3c, 0x63, 0x94,
+	0x13, 0x84, 0x61, 0x3b, 0x2d, 0x20, 0xac, 0x59, 0xba, 0x63, 0xf8, 0xf3, 0x72, 0x26, 0xf3, 0x1a,
+	0x88, 0x30, 0x56, 0x6d, 0x1c, 0x82, 0x29, 0x81, 0x6e, 0x1c, 0xd0, 0x88, 0x6c, 0x72, 0x3c, 0x3a,
+	0x81, 0x5d, 0x32, 0xcd, 0x92, 0x9c, 0xf0, 0x8f, 0x31, 0x4d, 0xa6, 0x58, 0xb7, 0x34, 0x47, 0xf7,
+	0xcd, 0x12, 0x7b, 0xa1, 0xc9, 0xb4, 0xf3, 0x53, 0x03, 0x90, 0x9f, 0xb9, 0x99, 0x1d, 0x8b, 0xae,
+	0xc1, 0x58, 0xe4, 0x89, 0x8e, 0x14, 0x1f, 0x56, 0x73, 0x6e, 0x35, 0x94, 0x76, 0x39, 0x73, 0x05,
+	0x3b, 0xf3, 0xf0, 0x51, 0x5b, 0x21, 0xac, 0xfc, 0x13, 0x7f, 0x8f, 0x3f, 0x81, 0x59, 0xf1, 0x0e,
+	0x1d, 0xab, 0x9c, 0x35, 0xaf, 0x5b, 0xd6, 0xff, 0x84, 0xd2, 0xf6, 0x47, 0x30, 0x2b, 0xe6, 0xae,
+	0x28, 0xae, 0xdb, 0xde, 0xc2, 0xeb, 0x6b, 0x15, 0x09, 0x9c, 0x6b, 0xb7, 0x67, 0x6f, 0xa7, 0x51,
+	0x22, 0xe2, 0xf1, 0xc0, 0x0d, 0xd9, 0xc8, 0xeb, 0x07, 0x79, 0x38, 0x09, 0x68, 0x9f, 0xa4, 0x29,
+	0xf1, 0xe4, 0x90, 0x27, 0x87, 0x06, 0x75, 0xf9, 0x50, 0x2e, 0x7e, 0x07, 0x00, 0x0c, 0xa1, 0x6c,
+	0x06, 0x37, 0x03, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// LevelAdminClient is the client API for LevelAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type LevelAdminClient interface {
+	// GetLevels returns the global level and the levels set for components
+	GetLevels(ctx context.Context, in *GetLevelsRequest, opts ...grpc.CallOption) (*Levels, error)
+	// SetLevel sets the global level, or the level of a component. An empty level clears the level of a component
+	SetLevel(ctx context.Context, in *SetLevelRequest, opts ...grpc.CallOption) (*Levels, error)
+	// ListLoggers returns the names of the named loggers created so far
+	ListLoggers(ctx context.Context, in *ListLoggersRequest, opts ...grpc.CallOption) (*ListLoggersResponse, error)
+	// WatchLevels streams every later change of the global and component levels
+	WatchLevels(ctx context.Context, in *WatchLevelsRequest, opts ...grpc.CallOption) (LevelAdmin_WatchLevelsClient, error)
+}
+
+type levelAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLevelAdminClient(cc grpc.ClientConnInterface) LevelAdminClient {
+	return &levelAdminClient{cc}
+}
+
+func (c *levelAdminClient) GetLevels(ctx context.Context, in *GetLevelsRequest, opts ...grpc.CallOption) (*Levels, error) {
+	out := new(Levels)
+	err := c.cc.Invoke(ctx, "/yawhg.admin.LevelAdmin/GetLevels", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *levelAdminClient) SetLevel(ctx context.Context, in *SetLevelRequest, opts ...grpc.CallOption) (*Levels, error) {
+	out := new(Levels)
+	err := c.cc.Invoke(ctx, "/yawhg.admin.LevelAdmin/SetLevel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *levelAdminClient) ListLoggers(ctx context.Context, in *ListLoggersRequest, opts ...grpc.CallOption) (*ListLoggersResponse, error) {
+	out := new(ListLoggersResponse)
+	err := c.cc.Invoke(ctx, "/yawhg.admin.LevelAdmin/ListLoggers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *levelAdminClient) WatchLevels(ctx context.Context, in *WatchLevelsRequest, opts ...grpc.CallOption) (LevelAdmin_WatchLevelsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_LevelAdmin_serviceDesc.Streams[0], "/yawhg.admin.LevelAdmin/WatchLevels", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &levelAdminWatchLevelsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type LevelAdmin_WatchLevelsClient interface {
+	Recv() (*LevelChange, error)
+	grpc.ClientStream
+}
+
+type levelAdminWatchLevelsClient struct {
+	grpc.ClientStream
+}
+
+func (x *levelAdminWatchLevelsClient) Recv() (*LevelChange, error) {
+	m := new(LevelChange)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LevelAdminServer is the server API for LevelAdmin service.
+type LevelAdminServer interface {
+	// GetLevels returns the global level and the levels set for components
+	GetLevels(context.Context, *GetLevelsRequest) (*Levels, error)
+	// SetLevel sets the global level, or the level of a component. An empty level clears the level of a component
+	SetLevel(context.Context, *SetLevelRequest) (*Levels, error)
+	// ListLoggers returns the names of the named loggers created so far
+	ListLoggers(context.Context, *ListLoggersRequest) (*ListLoggersResponse, error)
+	// WatchLevels streams every later change of the global and component levels
+	WatchLevels(*WatchLevelsRequest, LevelAdmin_WatchLevelsServer) error
+}
+
+// UnimplementedLevelAdminServer can be embedded to have forward compatible implementations.
+type UnimplementedLevelAdminServer struct {
+}
+
+func (*UnimplementedLevelAdminServer) GetLevels(ctx context.Context, req *GetLevelsRequest) (*Levels, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLevels not implemented")
+}
+func (*UnimplementedLevelAdminServer) SetLevel(ctx context.Context, req *SetLevelRequest) (*Levels, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLevel not implemented")
+}
+func (*UnimplementedLevelAdminServer) ListLoggers(ctx context.Context, req *ListLoggersRequest) (*ListLoggersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLoggers not implemented")
+}
+func (*UnimplementedLevelAdminServer) WatchLevels(req *WatchLevelsRequest, srv LevelAdmin_WatchLevelsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchLevels not implemented")
+}
+
+func RegisterLevelAdminServer(s *grpc.Server, srv LevelAdminServer) {
+	s.RegisterService(&_LevelAdmin_serviceDesc, srv)
+}
+
+func _LevelAdmin_GetLevels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLevelsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LevelAdminServer).GetLevels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/yawhg.admin.LevelAdmin/GetLevels",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LevelAdminServer).GetLevels(ctx, req.(*GetLevelsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LevelAdmin_SetLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LevelAdminServer).SetLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/yawhg.admin.LevelAdmin/SetLevel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LevelAdminServer).SetLevel(ctx, req.(*SetLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LevelAdmin_ListLoggers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLoggersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LevelAdminServer).ListLoggers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/yawhg.admin.LevelAdmin/ListLoggers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LevelAdminServer).ListLoggers(ctx, req.(*ListLoggersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LevelAdmin_WatchLevels_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchLevelsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LevelAdminServer).WatchLevels(m, &levelAdminWatchLevelsServer{stream})
+}
+
+type LevelAdmin_WatchLevelsServer interface {
+	Send(*LevelChange) error
+	grpc.ServerStream
+}
+
+type levelAdminWatchLevelsServer struct {
+	grpc.ServerStream
+}
+
+func (x *levelAdminWatchLevelsServer) Send(m *LevelChange) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _LevelAdmin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "yawhg.admin.LevelAdmin",
+	HandlerType: (*LevelAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetLevels",
+			Handler:    _LevelAdmin_GetLevels_Handler,
+		},
+		{
+			MethodName: "SetLevel",
+			Handler:    _LevelAdmin_SetLevel_Handler,
+		},
+		{
+			MethodName: "ListLoggers",
+			Handler:    _LevelAdmin_ListLoggers_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchLevels",
+			Handler:       _LevelAdmin_WatchLevels_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "admin.proto",
+}
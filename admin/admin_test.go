@@ -0,0 +1,82 @@
+package admin_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/MarcvanMelle/yawhg"
+	"github.com/MarcvanMelle/yawhg/admin"
+)
+
+func TestLevelAdmin(t *testing.T) {
+	yawhg.ConfigYawhg(yawhg.Options{Enabled: false, LogLevel: "InfoLevel"})
+	defer yawhg.ConfigYawhg(yawhg.Options{Enabled: true, LogLevel: "InfoLevel"})
+	defer yawhg.ClearComponentLevel("billing")
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	admin.Register(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := admin.NewLevelAdminClient(conn)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watch, err := client.WatchLevels(ctx, &admin.WatchLevelsRequest{})
+	require.NoError(t, err)
+	// the stream is subscribed once the server has sent its headers
+	_, err = watch.Header()
+	require.NoError(t, err)
+
+	levels, err := client.SetLevel(ctx, &admin.SetLevelRequest{Level: "debug"})
+	require.NoError(t, err)
+	assert.Equal(t, "debug", levels.Level)
+	assert.Equal(t, yawhg.DebugLevel, yawhg.GetLevel())
+
+	levels, err = client.SetLevel(ctx, &admin.SetLevelRequest{Component: "billing", Level: "error"})
+	require.NoError(t, err)
+	assert.Equal(t, []*admin.ComponentLevel{{Component: "billing", Level: "error"}}, levels.Components)
+
+	_, err = client.SetLevel(ctx, &admin.SetLevelRequest{Level: "verbose"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	levels, err = client.GetLevels(ctx, &admin.GetLevelsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "debug", levels.Level)
+	assert.Len(t, levels.Components, 1)
+
+	levels, err = client.SetLevel(ctx, &admin.SetLevelRequest{Component: "billing"})
+	require.NoError(t, err)
+	assert.Empty(t, levels.Components)
+
+	yawhg.Named("billing.invoices")
+	loggers, err := client.ListLoggers(ctx, &admin.ListLoggersRequest{})
+	require.NoError(t, err)
+	assert.Contains(t, loggers.Loggers, "billing.invoices")
+
+	for _, expected := range []admin.LevelChange{
+		{Level: "debug"},
+		{Component: "billing", Level: "error"},
+		{Component: "billing"},
+	} {
+		change, err := watch.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, expected.Component, change.Component)
+		assert.Equal(t, expected.Level, change.Level)
+	}
+}
@@ -4,8 +4,12 @@ go 1.13
 
 require (
 	github.com/gofrs/uuid v3.2.0+incompatible
-	github.com/stretchr/testify v1.4.0
+	github.com/golang/protobuf v1.3.3
+	github.com/stretchr/testify v1.7.0
+	go.opentelemetry.io/otel v1.0.0
+	go.opentelemetry.io/otel/trace v1.0.0
 	golang.org/x/text v0.3.2 // indirect
 	google.golang.org/genproto v0.0.0-20200207204624-4f3edf09f4f6 // indirect
 	google.golang.org/grpc v1.27.0
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
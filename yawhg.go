@@ -9,15 +9,92 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// RequestIDHeader is the default header and metadata key of the request ID
 const RequestIDHeader string = "x-request-id"
 const requestIDKey string = "request_id"
 
 var Destination io.Writer
 
-var appVersion string
-var appLogLevel Level
+// destinationMu is held while a log is written to the Destination, and while ConfigYawhg replaces it, so that a
+// replaced Destination is no longer written to once ConfigYawhg returns
+var destinationMu sync.RWMutex
+
+// runtimeConfig is the configuration set by ConfigYawhg and the level setters, which is replaced as a whole and never
+// modified, so that a log or a request read once is handled entirely with either the previous or the next configuration
+type runtimeConfig struct {
+	// level is the global level, and componentLevels the levels set for components, which are replaced rather than
+	// modified, see updateConfig
+	level           Level
+	componentLevels map[string]Level
+
+	appVersion            string
+	logStreamMessages     bool
+	clientLogLevel        Level
+	clientErrorLogLevel   Level
+	clientLogHeaders      []string
+	recordErrorSpanEvents bool
+
+	maxBodyLogSize     int
+	bodyLogSkipPaths   []string
+	redactedFormFields []string
+
+	bufferRequestLogs   bool
+	requestBufferSize   int
+	levelOverrideSecret []byte
+	levelOverrideHosts  []string
+
+	sampler      *logSampler
+	deduper      *logDeduper
+	errorHandler ErrorHandler
+
+	// requestIDHeader is the header and metadata key of the request ID, in lowercase as required by gRPC metadata
+	requestIDHeader   string
+	generateRequestID RequestIDGenerator
+	validateRequestID RequestIDValidator
+	extractFormats    []string
+	injectFormats     []string
+}
+
+var activeConfig atomic.Value
+
+// configMu serializes the replacements of the configuration
+var configMu sync.Mutex
+
+// currentConfig returns the configuration set by the last call to ConfigYawhg, along with the levels set since
+func currentConfig() *runtimeConfig {
+	return activeConfig.Load().(*runtimeConfig)
+}
+
+// updateConfig replaces the configuration with a modified copy, e.g. to change the level
+func updateConfig(fn func(cfg *runtimeConfig)) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	cfg := *currentConfig()
+	fn(&cfg)
+	activeConfig.Store(&cfg)
+}
+
+// defaultConfig returns the configuration of yawhg until ConfigYawhg is called
+func defaultConfig() *runtimeConfig {
+	return &runtimeConfig{
+		level:               InfoLevel,
+		clientLogLevel:      InfoLevel,
+		clientErrorLogLevel: ErrorLevel,
+		maxBodyLogSize:      defaultMaxBodyLogSize,
+		requestBufferSize:   defaultRequestBufferSize,
+		errorHandler:        stderrErrorHandler,
+		requestIDHeader:     RequestIDHeader,
+		generateRequestID:   NewUUIDv4,
+		validateRequestID:   ValidRequestID,
+		extractFormats:      defaultFormats,
+		injectFormats:       defaultFormats,
+	}
+}
 
 // fieldsPool caches allocated but unused items for later reuse,
 // relieving pressure on the garbage collector.
@@ -25,30 +102,188 @@ var fieldsPool *sync.Pool
 
 // Options is a struct containing initialization options for yawhg
 // Disabled controls whether or not the logs will be output to os.Stdout or disposed (useful for test environments)
+// Output replaces the Destination of the logs when set, e.g. os.Stderr
 // AppVersion is the version of the current application.  It will be attached to all logs for troubleshooting purposes.
+// LogStreamMessages enables a debug-level log for every message sent or received on a gRPC stream
+// ClientLogLevel and ClientErrorLogLevel are the levels at which outbound calls are logged when they succeed or fail
+// ClientLogHeaders lists the headers of outbound requests logged by Transport, none by default. The Authorization,
+// Cookie, Proxy-Authorization and x-yawhg-level headers, and those redacted with WithRedaction, are logged as REDACTED
+// MaxBodyLogSize is the number of bytes of request bodies logged by HTTPLogMiddleware, 4096 by default
+// BodyLogSkipPaths lists the request paths whose bodies are never logged, where a trailing "*" matches any suffix
+// RedactedFormFields lists the form values which are redacted when logging form request bodies
+// ExtractFormats lists the propagation formats read from incoming requests in order of precedence, and InjectFormats
+// the formats written to outgoing requests. Both default to RequestIDFormat and W3CFormat
+// RecordErrorSpanEvents records error-level logs as events of the active OpenTelemetry span of their context
+// BufferRequestLogs buffers the logs of each request below the LogLevel, which are written if the request logs an error
+// or fails, and discarded otherwise. Up to RequestBufferSize logs are buffered per request, 100 by default
+// LevelOverrideSecret is the secret of the signed x-yawhg-level header, which sets the log level of a single request.
+// The header is ignored unless the secret is set, see SignLevelOverride
+// LevelOverrideHosts lists the hosts, with or without their port, to which Transport and the gRPC client interceptors
+// forward the level override of a request. Overrides are not forwarded by default, since the header can be replayed
+// by its recipients until it expires
+// LevelRules sets the levels of components logging through named loggers, e.g. "billing=debug,billing.pdf=error,*=info",
+// where a rule applies to the component and its children, and "*" overrides the LogLevel.
+// The rules are ignored if any is invalid, which is reported to the ErrorHandler
+// SamplingRules sample the logs of levels or messages which are written too often, counting them over each
+// SamplingInterval, 1 second by default. The number of suppressed logs of each message is logged at the end of every
+// interval. Rules with an invalid level are disabled and reported to the ErrorHandler. With SampleByRequestID, the logs
+// of requests are instead written for a share of the request IDs, so that the sampled requests keep all of their logs
+// DedupWindow enables the suppression of duplicate logs, which have the same level and fields apart from their time,
+// request and trace IDs and ResponseTime, or else the same level, message and DedupKeys when set. The first log
+// is written, and its duplicates within the window are summarized once it has elapsed, with their number as repeated.
+// Up to DedupMaxEntries distinct logs are tracked, 1000 by default
+// ErrorHandler receives the internal errors of yawhg, such as logs which fail to be encoded or written, which are
+// reported as a structured line on stderr by default. It is called without holding any lock, and may log through
+// yawhg, in which case the errors of its own logs are reported on stderr
+// RequestIDHeader is the header and metadata key of the request ID, "x-request-id" by default
+// RequestIDGenerator generates the request IDs of requests which arrive without one, NewUUIDv4 by default
+// RequestIDValidator replaces incoming request IDs which it rejects with generated ones, ValidRequestID by default
 type Options struct {
-	AppVersion string
-	Enabled    bool
-	LogLevel   string
+	AppVersion          string
+	Enabled             bool
+	Output              io.Writer
+	LogLevel            string
+	LogStreamMessages   bool
+	ClientLogLevel      string
+	ClientErrorLogLevel string
+	ClientLogHeaders    []string
+	MaxBodyLogSize      int
+	BodyLogSkipPaths    []string
+	RedactedFormFields  []string
+	ExtractFormats      []string
+	InjectFormats       []string
+
+	RecordErrorSpanEvents bool
+	BufferRequestLogs     bool
+	RequestBufferSize     int
+	LevelOverrideSecret   string
+	LevelOverrideHosts    []string
+	LevelRules            string
+
+	SamplingRules     []SamplingRule
+	SamplingInterval  time.Duration
+	SampleByRequestID bool
+
+	DedupWindow     time.Duration
+	DedupKeys       []string
+	DedupMaxEntries int
+
+	ErrorHandler ErrorHandler
+
+	RequestIDHeader    string
+	RequestIDGenerator RequestIDGenerator
+	RequestIDValidator RequestIDValidator
 }
 
 // ConfigYawhg overrides the default yawgh initialization with custom options
+// The configuration, including the level, the level rules and the Destination, is replaced at once, so that each log
+// and each request handled by the middleware and interceptors uses either the previous or the next configuration,
+// even while logs are written concurrently. Invalid options, see Options.Validate, are reported to the ErrorHandler
 func ConfigYawhg(options Options) {
+	var replaced *runtimeConfig
+	var errs []error
+	defer func() {
+		// the previous configuration is retired and the errors are reported without holding configMu, since the
+		// ErrorHandler may log through yawhg
+		if replaced != nil {
+			replaced.retire()
+		}
+		for _, err := range errs {
+			handleError(err)
+		}
+	}()
+
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	replaced = currentConfig()
+	if err := options.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("configuring yawhg with invalid options: %v", err))
+	}
+
+	cfg := defaultConfig()
+	cfg.appVersion = options.AppVersion
+	cfg.logStreamMessages = options.LogStreamMessages
+
+	// the level replaces any temporary level, and the level rules are compared with the previous ones to notify the
+	// subscribers of the levels which changed
+	stopTemporaryLevel()
+	baseLogLevel = optionLevel(options.LogLevel, InfoLevel)
+	cfg.level = baseLogLevel
+	cfg.componentLevels = replaced.componentLevels
+	levelRules, err := ParseLevelRules(options.LevelRules)
+	if err != nil {
+		levelRules = nil // reported by Validate
+	}
+	levelChanges := append([]LevelChange{{Level: cfg.level.String()}}, cfg.applyLevelRules(levelRules)...)
+
+	cfg.sampler = newSampler(options.SamplingRules, options.SamplingInterval, options.SampleByRequestID)
+	cfg.deduper = newDeduper(options.DedupWindow, options.DedupKeys, options.DedupMaxEntries)
+
+	cfg.clientLogLevel = optionLevel(options.ClientLogLevel, InfoLevel)
+	cfg.clientErrorLogLevel = optionLevel(options.ClientErrorLogLevel, ErrorLevel)
+	cfg.clientLogHeaders = options.ClientLogHeaders
+
+	if options.MaxBodyLogSize > 0 {
+		cfg.maxBodyLogSize = options.MaxBodyLogSize
+	}
+	cfg.bodyLogSkipPaths = options.BodyLogSkipPaths
+	cfg.redactedFormFields = options.RedactedFormFields
+	cfg.recordErrorSpanEvents = options.RecordErrorSpanEvents
+
+	cfg.bufferRequestLogs = options.BufferRequestLogs
+	if options.RequestBufferSize > 0 {
+		cfg.requestBufferSize = options.RequestBufferSize
+	}
+
+	if options.ErrorHandler != nil {
+		cfg.errorHandler = options.ErrorHandler
+	}
+
+	cfg.levelOverrideSecret = []byte(options.LevelOverrideSecret)
+	cfg.levelOverrideHosts = options.LevelOverrideHosts
+
+	if options.RequestIDHeader != "" {
+		cfg.requestIDHeader = strings.ToLower(options.RequestIDHeader)
+	}
+	if options.RequestIDGenerator != nil {
+		cfg.generateRequestID = options.RequestIDGenerator
+	}
+	if options.RequestIDValidator != nil {
+		cfg.validateRequestID = options.RequestIDValidator
+	}
+
+	if len(options.ExtractFormats) > 0 {
+		cfg.extractFormats = options.ExtractFormats
+	}
+	if len(options.InjectFormats) > 0 {
+		cfg.injectFormats = options.InjectFormats
+	}
+
+	destinationMu.Lock()
 	if !options.Enabled {
 		Destination = ioutil.Discard
+	} else if options.Output != nil {
+		Destination = options.Output
 	}
+	activeConfig.Store(cfg)
+	destinationMu.Unlock()
 
-	appVersion = options.AppVersion
+	for _, change := range levelChanges {
+		levelChanged(change)
+	}
+}
 
-	switch options.LogLevel {
-	case "DebugLevel":
-		appLogLevel = DebugLevel
-	case "InfoLevel":
-		appLogLevel = InfoLevel
-	case "ErrorLevel":
-		appLogLevel = ErrorLevel
-	default:
-		appLogLevel = InfoLevel
+// retire writes the logs held back by the configuration once it has been replaced, i.e. the reports of the sampled
+// logs and the summaries of the duplicate logs
+func (cfg *runtimeConfig) retire() {
+	if cfg.sampler != nil {
+		cfg.sampler.stop()
+	}
+	if cfg.deduper != nil {
+		cfg.deduper.flush()
 	}
 }
 
@@ -69,18 +304,25 @@ func WithFields(details Fields, errors ...error) *Fields {
 
 // WithTracing behaved like WithFields, but in addition, will extract tracing information from the supplied context struct and add it to the fields map to be logged
 // The log will be serialized and written when a level is called, e.g. yawhg.WithFields({}).Info("message")
+// Only the tracing information is kept, not the context, so that the logger may outlive the request. The level override
+// and buffer of a request apply to the logs of the functions taking its context, e.g. yawhg.DebugWithTracing
 func WithTracing(ctx context.Context, details Fields, errors ...error) *Fields {
+	return withTracing(ctx, currentConfig(), details, errors...)
+}
+
+func withTracing(ctx context.Context, cfg *runtimeConfig, details Fields, errors ...error) *Fields {
 	addErrors(details, errors)
 	// make a copy of the map values to prevent a data race during concurrent calls
 	data := details.Copy()
-	ctx = data.addTracing(ctx)
+	data.addTracing(ctx, cfg)
 
 	return &data
 }
 
 func init() {
 	Destination = os.Stdout
-	appLogLevel = InfoLevel
+	baseLogLevel = InfoLevel
+	activeConfig.Store(defaultConfig())
 
 	fieldsPool = &sync.Pool{
 		New: func() interface{} {
@@ -89,41 +331,34 @@ func init() {
 	}
 }
 
-func structuredWrap(msgMap *Fields) {
-	msgMap.addBaseFields()
+func structuredWrap(ctx context.Context, msgMap *Fields) {
+	cfg := currentConfig()
 
-	messageLevel, err := msgMap.checkSeverityLevel()
-	if err != nil {
-		fmt.Printf("checking log message severity level: %v", err)
-		messageLevel = InfoLevel // default to InfoLevel in case of error
-	}
+	msgMap.addBaseFields(cfg)
+	msgMap.write(ctx, cfg)
+}
 
-	// only write the log if the severity level rises to the specified threshold
-	if messageLevel >= appLogLevel {
-		msgMap.fire()
-	}
+// tracingWrap writes the fields along with the tracing information of the context
+func tracingWrap(ctx context.Context, msgMap *Fields) {
+	cfg := currentConfig()
+
+	msgMap.addBaseFields(cfg)
+	ctx = msgMap.addTracing(ctx, cfg)
+	msgMap.write(ctx, cfg)
 }
 
 func textWrap(ctx context.Context, msg string, level string) {
+	cfg := currentConfig()
+
 	data := fieldsPool.Get().(*Fields)
 	defer fieldsPool.Put(data)
 	defer data.resetWrapper() // defers are executed as LIFO per https://blog.golang.org/defer-panic-and-recover
 	(*data)["severity"] = level
 	(*data)["msg"] = msg
 
-	data.addBaseFields()
-	ctx = data.addTracing(ctx)
-
-	messageLevel, err := data.checkSeverityLevel()
-	if err != nil {
-		fmt.Printf("checking log message severity level: %v", err)
-		messageLevel = InfoLevel // default to InfoLevel in case of error
-	}
-
-	// only write the log if the severity level rises to the specified threshold
-	if messageLevel >= appLogLevel {
-		data.fire()
-	}
+	data.addBaseFields(cfg)
+	ctx = data.addTracing(ctx, cfg)
+	data.write(ctx, cfg)
 }
 
 // add a concatenation of non-nil errors to the "Error" field
@@ -0,0 +1,121 @@
+package yawhg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// levelState is the JSON representation of the level served by LevelHandler
+type levelState struct {
+	Level   string     `json:"level"`
+	TTL     string     `json:"ttl,omitempty"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// LevelHandler serves the level from which logs are written as JSON, e.g. {"level":"info"}.
+// GET returns the current level, and PUT sets it. A PUT with a ttl such as {"level":"debug","ttl":"10m"}
+// sets a temporary level, which reverts once the ttl has elapsed
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			if err := putLevel(r); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		state := levelState{Level: GetLevel().String()}
+		if expiry := levelExpiresAt(); !expiry.IsZero() {
+			state.Expires = &expiry
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(state)
+	})
+}
+
+// putLevel sets the level of a PUT request of LevelHandler
+func putLevel(r *http.Request) error {
+	var state levelState
+	if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+		return fmt.Errorf("decoding level: %v", err)
+	}
+
+	level, err := ParseLevel(state.Level)
+	if err != nil {
+		return err
+	}
+
+	previous := GetLevel()
+	if state.TTL == "" {
+		SetLevel(level)
+		WithFields(Fields{"Level": level.String(), "PreviousLevel": previous.String()}).log(context.Background(), currentConfig(), InfoLevel, "log level changed")
+		return nil
+	}
+
+	ttl, err := time.ParseDuration(state.TTL)
+	if err != nil || ttl <= 0 {
+		return fmt.Errorf("not a valid ttl: %q", state.TTL)
+	}
+
+	SetTemporaryLevel(level, ttl)
+	WithFields(Fields{"Level": level.String(), "PreviousLevel": previous.String(), "TTL": state.TTL}).log(context.Background(), currentConfig(), InfoLevel, "log level changed")
+	return nil
+}
+
+// LevelChange describes a change of the global level, or of the level of a component if Component is set.
+// Level is empty when the level of a component is cleared, and Expires is set for a temporary level
+type LevelChange struct {
+	Component string
+	Level     string
+	Expires   time.Time
+}
+
+// levelChangeBuffer is the number of level changes buffered per subscriber, beyond which changes are dropped
+const levelChangeBuffer int = 16
+
+var subscribersMu sync.Mutex
+var levelSubscribers = map[chan LevelChange]struct{}{}
+
+// SubscribeLevelChanges returns a channel receiving every later change of the global and component levels,
+// until unsubscribe is called. Changes are dropped for subscribers which do not keep up
+func SubscribeLevelChanges() (changes <-chan LevelChange, unsubscribe func()) {
+	ch := make(chan LevelChange, levelChangeBuffer)
+
+	subscribersMu.Lock()
+	levelSubscribers[ch] = struct{}{}
+	subscribersMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			subscribersMu.Lock()
+			delete(levelSubscribers, ch)
+			subscribersMu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// levelChanged notifies the subscribers of a level change without blocking
+func levelChanged(change LevelChange) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	for ch := range levelSubscribers {
+		select {
+		case ch <- change:
+		default:
+		}
+	}
+}
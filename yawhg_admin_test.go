@@ -0,0 +1,184 @@
+package yawhg_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MarcvanMelle/yawhg"
+)
+
+var levelHandlerTestCases = []struct {
+	name           string
+	method         string
+	body           string
+	expectedStatus int
+	expectedLevel  yawhg.Level
+	expectedBody   string
+}{
+	{
+		name:           "get_the_current_level",
+		method:         "GET",
+		expectedStatus: http.StatusOK,
+		expectedLevel:  yawhg.InfoLevel,
+		expectedBody:   `{"level":"info"}`,
+	},
+	{
+		name:           "set_the_level",
+		method:         "PUT",
+		body:           `{"level":"debug"}`,
+		expectedStatus: http.StatusOK,
+		expectedLevel:  yawhg.DebugLevel,
+		expectedBody:   `{"level":"debug"}`,
+	},
+	{
+		name:           "set_a_temporary_level",
+		method:         "PUT",
+		body:           `{"level":"error","ttl":"10m"}`,
+		expectedStatus: http.StatusOK,
+		expectedLevel:  yawhg.ErrorLevel,
+		expectedBody:   `"expires":`,
+	},
+	{
+		name:           "reject_an_unknown_level",
+		method:         "PUT",
+		body:           `{"level":"verbose"}`,
+		expectedStatus: http.StatusBadRequest,
+		expectedLevel:  yawhg.InfoLevel,
+	},
+	{
+		name:           "reject_an_invalid_ttl",
+		method:         "PUT",
+		body:           `{"level":"debug","ttl":"soon"}`,
+		expectedStatus: http.StatusBadRequest,
+		expectedLevel:  yawhg.InfoLevel,
+	},
+	{
+		name:           "reject_other_methods",
+		method:         "POST",
+		expectedStatus: http.StatusMethodNotAllowed,
+		expectedLevel:  yawhg.InfoLevel,
+	},
+}
+
+func TestLevelHandler(t *testing.T) {
+	for _, testCase := range levelHandlerTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "InfoLevel"})
+			defer restore()
+
+			w := httptest.NewRecorder()
+			yawhg.LevelHandler().ServeHTTP(w, httptest.NewRequest(testCase.method, "/level", strings.NewReader(testCase.body)))
+
+			assert.Equal(t, testCase.expectedStatus, w.Code)
+			assert.Equal(t, testCase.expectedLevel, yawhg.GetLevel())
+			assert.Contains(t, w.Body.String(), testCase.expectedBody)
+		})
+	}
+}
+
+func TestSetTemporaryLevel(t *testing.T) {
+	_, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "InfoLevel"})
+	defer restore()
+
+	yawhg.SetTemporaryLevel(yawhg.DebugLevel, 10*time.Millisecond)
+	assert.Equal(t, yawhg.DebugLevel, yawhg.GetLevel())
+
+	assert.Eventually(t, func() bool {
+		return yawhg.GetLevel() == yawhg.InfoLevel
+	}, time.Second, 5*time.Millisecond)
+
+	// a level set in the meantime is not reverted
+	yawhg.SetTemporaryLevel(yawhg.DebugLevel, 10*time.Millisecond)
+	yawhg.SetLevel(yawhg.ErrorLevel)
+
+	assert.Never(t, func() bool {
+		return yawhg.GetLevel() != yawhg.ErrorLevel
+	}, 50*time.Millisecond, 5*time.Millisecond)
+}
+
+func TestComponentLevel(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "InfoLevel"})
+	defer restore()
+
+	yawhg.SetComponentLevel("billing", yawhg.DebugLevel)
+	defer yawhg.ClearComponentLevel("billing")
+
+	yawhg.Named("billing").Debug("billing detail")
+	yawhg.Named("search").Debug("search detail")
+	yawhg.WithFields(yawhg.Fields{}).Debug("global detail")
+
+	assert.Contains(t, actualResult.String(), `"logger":"billing","msg":"billing detail"`)
+	assert.NotContains(t, actualResult.String(), "search detail")
+	assert.NotContains(t, actualResult.String(), "global detail")
+	assert.Contains(t, yawhg.Loggers(), "search")
+}
+
+var levelRulesTestCases = []struct {
+	name          string
+	logger        string
+	expectedDebug bool
+	expectedInfo  bool
+}{
+	{
+		name:          "apply_the_rule_of_the_component",
+		logger:        "billing",
+		expectedDebug: true,
+		expectedInfo:  true,
+	},
+	{
+		name:          "apply_the_rule_of_the_closest_parent",
+		logger:        "billing.invoices",
+		expectedDebug: true,
+		expectedInfo:  true,
+	},
+	{
+		name:   "apply_the_rule_of_a_child_over_its_parent",
+		logger: "billing.pdf.render",
+	},
+	{
+		name:         "apply_the_global_rule_to_other_components",
+		logger:       "search",
+		expectedInfo: true,
+	},
+	{
+		name:         "not_match_components_sharing_a_prefix",
+		logger:       "billingsvc",
+		expectedInfo: true,
+	},
+}
+
+func TestLevelRules(t *testing.T) {
+	for _, testCase := range levelRulesTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actualResult, restore := captureLogs(yawhg.Options{
+				Enabled:    true,
+				AppVersion: "test",
+				LogLevel:   "ErrorLevel",
+				LevelRules: "billing=debug, billing.pdf=error, *=info",
+			})
+			defer restore()
+
+			yawhg.Named(testCase.logger).Debug("debug detail")
+			yawhg.Named(testCase.logger).Info("info detail")
+
+			assert.Equal(t, testCase.expectedDebug, strings.Contains(actualResult.String(), "debug detail"))
+			assert.Equal(t, testCase.expectedInfo, strings.Contains(actualResult.String(), "info detail"))
+		})
+	}
+}
+
+func TestParseLevelRules(t *testing.T) {
+	levels, err := yawhg.ParseLevelRules("billing=debug,,*=error")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]yawhg.Level{"billing": yawhg.DebugLevel, "*": yawhg.ErrorLevel}, levels)
+
+	for _, rules := range []string{"billing", "=debug", "billing=verbose"} {
+		_, err := yawhg.ParseLevelRules(rules)
+		assert.Error(t, err, rules)
+	}
+}
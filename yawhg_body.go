@@ -0,0 +1,117 @@
+package yawhg
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultMaxBodyLogSize is the number of bytes of a request body captured for logging unless configured otherwise
+const defaultMaxBodyLogSize int = 4096
+
+// bodyReader restores a request body after its beginning has been read for logging
+type bodyReader struct {
+	io.Reader
+	io.Closer
+}
+
+// logRequestBody captures the beginning of the request body for logging, and returns a copy of the request whose body
+// can still be read in full by downstream handlers
+func logRequestBody(r *http.Request, cfg *runtimeConfig, payload Fields) *http.Request {
+	if skipBodyLog(r.URL.Path, cfg.bodyLogSkipPaths) {
+		return r
+	}
+
+	if r.Body == nil || r.Body == http.NoBody {
+		payload["RequestBody"] = ""
+		return r
+	}
+
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if !isTextContent(contentType) {
+		// binary and multipart content is summarised, and left unread
+		payload["RequestBody"] = Fields{
+			"ContentType": contentType,
+			"Size":        r.ContentLength,
+		}
+		return r
+	}
+
+	captured, _ := ioutil.ReadAll(io.LimitReader(r.Body, int64(cfg.maxBodyLogSize)+1))
+
+	// https://godoc.org/net/http#Handler - "Except for reading the body, handlers should not modify the provided Request."
+	// We create a shallow copy of the request, restore the body of the copy, and return that
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.Body = bodyReader{Reader: io.MultiReader(bytes.NewReader(captured), r.Body), Closer: r.Body}
+
+	truncated := len(captured) > cfg.maxBodyLogSize
+	if truncated {
+		captured = captured[:cfg.maxBodyLogSize]
+		payload["RequestBodyTruncated"] = true
+	}
+
+	switch {
+	case isJSONContent(contentType) && !truncated && json.Valid(captured):
+		payload["RequestBody"] = json.RawMessage(captured)
+	case contentType == "application/x-www-form-urlencoded":
+		if truncated {
+			// drop the last, partially captured value, whose name may be cut short of a redacted name
+			captured = captured[:bytes.LastIndexByte(captured, '&')+1]
+		}
+
+		form, err := url.ParseQuery(string(captured))
+		if err != nil {
+			payload["RequestBody"] = string(captured)
+			break
+		}
+
+		for _, field := range cfg.redactedFormFields {
+			if values, ok := form[field]; ok {
+				for i := range values {
+					values[i] = redactedValue
+				}
+			}
+		}
+		payload["RequestBody"] = form
+	default:
+		payload["RequestBody"] = string(captured)
+	}
+
+	return r2
+}
+
+// skipBodyLog reports whether the body of requests to the path must not be captured.
+// Skipped paths ending in "*" match every path with that prefix
+func skipBodyLog(path string, skippedPaths []string) bool {
+	for _, skipped := range skippedPaths {
+		if strings.HasSuffix(skipped, "*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(skipped, "*")) {
+				return true
+			}
+		} else if path == skipped {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isTextContent reports whether the body of the content type can be logged as text, assuming text when the type is
+// unknown
+func isTextContent(contentType string) bool {
+	return contentType == "" ||
+		strings.HasPrefix(contentType, "text/") ||
+		contentType == "application/x-www-form-urlencoded" ||
+		contentType == "application/xml" ||
+		isJSONContent(contentType)
+}
+
+func isJSONContent(contentType string) bool {
+	return contentType == "application/json" || strings.HasSuffix(contentType, "+json")
+}
@@ -0,0 +1,110 @@
+package yawhg
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultRequestBufferSize is the number of logs buffered per request unless configured otherwise
+const defaultRequestBufferSize int = 100
+
+// maxBufferedRequests bounds the number of requests whose logs are buffered at once
+const maxBufferedRequests int64 = 10000
+
+// bufferedRequests counts the requests whose logs are being buffered, accessed atomically
+var bufferedRequests int64
+
+type requestBufferKey struct{}
+
+// requestBuffer holds the encoded logs below the configured level of a request in flight. It is attached to the
+// context of the request rather than looked up by request ID, since concurrent requests may share a request ID
+type requestBuffer struct {
+	size int
+
+	mu    sync.Mutex
+	logs  [][]byte
+	ended bool
+}
+
+// startRequestBuffer attaches a new buffer to the context of a request, which buffers its logs below the configured
+// level. The buffer is nil if the logs of the request are not buffered
+func startRequestBuffer(ctx context.Context, cfg *runtimeConfig) (context.Context, *requestBuffer) {
+	if !cfg.bufferRequestLogs {
+		return ctx, nil
+	}
+
+	if atomic.AddInt64(&bufferedRequests, 1) > maxBufferedRequests {
+		atomic.AddInt64(&bufferedRequests, -1)
+		return ctx, nil
+	}
+
+	buffer := &requestBuffer{size: cfg.requestBufferSize}
+	return context.WithValue(ctx, requestBufferKey{}, buffer), buffer
+}
+
+// end stops buffering the logs of the request, and writes them if the request failed
+func (b *requestBuffer) end(failed bool) {
+	if b == nil {
+		return
+	}
+
+	atomic.AddInt64(&bufferedRequests, -1)
+
+	b.mu.Lock()
+	logs := b.logs
+	b.logs = nil
+	b.ended = true
+	b.mu.Unlock()
+
+	if failed {
+		writeBuffered(logs)
+	}
+}
+
+// flush writes the logs buffered so far for the request, and keeps buffering its later logs
+func (b *requestBuffer) flush() {
+	b.mu.Lock()
+	logs := b.logs
+	b.logs = nil
+	b.mu.Unlock()
+
+	writeBuffered(logs)
+}
+
+// bufferLog buffers the fields if the request of the context is being buffered, dropping the oldest log of a full
+// buffer. The fields are encoded right away, since they may be modified once logged
+func (f *Fields) bufferLog(ctx context.Context, level Level) {
+	buffer, ok := ctx.Value(requestBufferKey{}).(*requestBuffer)
+	if !ok || buffer.isEnded() {
+		return
+	}
+
+	f.runHooks(ctx, level)
+	encoded := f.encode()
+
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+
+	// the request may have ended while the log was encoded
+	if buffer.ended {
+		return
+	}
+
+	if len(buffer.logs) >= buffer.size {
+		buffer.logs = buffer.logs[1:]
+	}
+	buffer.logs = append(buffer.logs, encoded)
+}
+
+func (b *requestBuffer) isEnded() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.ended
+}
+
+func writeBuffered(logs [][]byte) {
+	for _, encoded := range logs {
+		writeLog(encoded)
+	}
+}
@@ -0,0 +1,165 @@
+package yawhg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const canonicalMessage string = "canonical log line"
+
+type canonicalKey struct{}
+
+// canonicalLogger is a cumulative logger for the lifetime of a request, which is safe for concurrent use
+type canonicalLogger struct {
+	mu     sync.Mutex
+	fields Fields
+}
+
+// newCanonicalContext attaches a new canonical logger to the context
+func newCanonicalContext(ctx context.Context) (context.Context, *canonicalLogger) {
+	logger := &canonicalLogger{fields: NewLogger()}
+	return context.WithValue(ctx, canonicalKey{}, logger), logger
+}
+
+// updateCanonical applies fn to the fields of the canonical logger of the context, if any
+func updateCanonical(ctx context.Context, fn func(f Fields)) {
+	logger, ok := ctx.Value(canonicalKey{}).(*canonicalLogger)
+	if !ok {
+		return
+	}
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	fn(logger.fields)
+}
+
+// Annotate adds a field to the canonical log line of the request of the context
+func Annotate(ctx context.Context, key string, value interface{}) {
+	updateCanonical(ctx, func(f Fields) {
+		f[key] = value
+	})
+}
+
+// Increment adds delta to a counter of the canonical log line of the request of the context
+func Increment(ctx context.Context, key string, delta int64) {
+	updateCanonical(ctx, func(f Fields) {
+		count, _ := f[key].(int64)
+		f[key] = count + delta
+	})
+}
+
+// StartTimer starts a timer of the canonical log line of the request of the context. Calling the returned function
+// adds the time elapsed to the timer in seconds, so that a timer can accumulate several measurements
+func StartTimer(ctx context.Context, key string) (stop func()) {
+	t := time.Now()
+
+	return func() {
+		elapsed := time.Since(t).Seconds()
+		updateCanonical(ctx, func(f Fields) {
+			total, _ := f[key].(float64)
+			f[key] = total + elapsed
+		})
+	}
+}
+
+// write logs the accumulated fields along with the payload, once the request has completed
+func (l *canonicalLogger) write(ctx context.Context, cfg *runtimeConfig, payload Fields, level Level) {
+	l.mu.Lock()
+	data := l.fields.Copy()
+	l.mu.Unlock()
+
+	for k, v := range payload {
+		data[k] = v
+	}
+
+	withTracing(ctx, cfg, data).log(ctx, cfg, level, canonicalMessage)
+}
+
+// CanonicalHTTPMiddleware logs a single summary of each request once it has completed, including everything added to
+// the request context with Annotate, Increment and StartTimer
+func CanonicalHTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := time.Now()
+		cfg := currentConfig()
+
+		ctx, requestID := fromContext(r.Context(), cfg)
+		ctx, logger := newCanonicalContext(ctx)
+		rw, recorder := wrapResponseWriter(w)
+
+		defer func() {
+			payload := Fields{
+				"Method":        r.Method,
+				"RequestPath":   r.URL.Path,
+				"RequestID":     requestID,
+				"Status":        recorder.Status(),
+				"ResponseBytes": recorder.bytes,
+				"ResponseTime":  time.Since(t).Seconds(),
+			}
+
+			if p := recover(); p != nil {
+				payload["Status"] = http.StatusInternalServerError
+				payload["Panic"] = fmt.Sprint(p)
+				logger.write(ctx, cfg, payload, ErrorLevel)
+
+				panic(p)
+			}
+
+			logger.write(ctx, cfg, payload, statusLevel(recorder.Status()))
+		}()
+
+		next.ServeHTTP(rw, r.WithContext(ctx))
+	})
+}
+
+// CanonicalGRPCInterceptor logs a single summary of each unary call once it has completed, including everything added
+// to the call context with Annotate, Increment and StartTimer
+func CanonicalGRPCInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	t := time.Now()
+	cfg := currentConfig()
+
+	ctx, requestID := fromContext(ctx, cfg)
+	ctx, logger := newCanonicalContext(ctx)
+
+	resp, err := handler(ctx, req)
+
+	logger.write(ctx, cfg, canonicalGRPCPayload(info.FullMethod, requestID, t, err), codeLevel(status.Code(err)))
+
+	return resp, err
+}
+
+// CanonicalGRPCStreamInterceptor logs a single summary of each stream once it has closed, including everything added
+// to the stream context with Annotate, Increment and StartTimer
+func CanonicalGRPCStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	t := time.Now()
+	cfg := currentConfig()
+
+	ctx, requestID := fromContext(ss.Context(), cfg)
+	ctx, logger := newCanonicalContext(ctx)
+
+	err := handler(srv, newServerStream(ss, ctx, cfg, info.FullMethod, requestID))
+
+	logger.write(ctx, cfg, canonicalGRPCPayload(info.FullMethod, requestID, t, err), codeLevel(status.Code(err)))
+
+	return err
+}
+
+func canonicalGRPCPayload(method string, requestID string, t time.Time, err error) Fields {
+	payload := Fields{
+		"Method":       method,
+		"RequestID":    requestID,
+		"StatusCode":   status.Code(err).String(),
+		"ResponseTime": time.Since(t).Seconds(),
+	}
+
+	if err != nil {
+		payload["Error"] = err.Error()
+	}
+
+	return payload
+}
@@ -0,0 +1,112 @@
+package yawhg
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCUnaryClientInterceptor propagates the request ID of the context to the outgoing metadata, and logs the outbound
+// call
+func GRPCUnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	t := time.Now()
+	cfg := currentConfig()
+
+	ctx, requestID := outgoingRequestID(ctx, cfg)
+	err := invoker(forwardLevelOverride(callContext(ctx, cfg), cfg, cc), method, req, reply, cc, opts...)
+
+	logClientCall(ctx, cfg, method, cc, requestID, t, err)
+
+	return err
+}
+
+// GRPCStreamClientInterceptor propagates the request ID of the context to the outgoing metadata, and logs the outbound
+// stream once it has finished or its context is done
+func GRPCStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	t := time.Now()
+	cfg := currentConfig()
+
+	ctx, requestID := outgoingRequestID(ctx, cfg)
+	cs, err := streamer(forwardLevelOverride(callContext(ctx, cfg), cfg, cc), desc, cc, method, opts...)
+	if err != nil {
+		logClientCall(ctx, cfg, method, cc, requestID, t, err)
+		return nil, err
+	}
+
+	stream := &clientStream{
+		ClientStream:  cs,
+		serverStreams: desc.ServerStreams,
+		done:          make(chan struct{}),
+		finish: func(err error) {
+			logClientCall(ctx, cfg, method, cc, requestID, t, err)
+		},
+	}
+	if ctx.Done() != nil {
+		go stream.watch(ctx)
+	}
+
+	return stream, nil
+}
+
+// clientStream wraps a grpc.ClientStream to log the call when the stream has finished
+type clientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	finish        func(err error)
+	once          sync.Once
+	done          chan struct{}
+}
+
+// RecvMsg detects the end of the stream, which is either an error, io.EOF, or the single response of a client stream
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == io.EOF {
+		s.end(nil)
+	} else if err != nil || !s.serverStreams {
+		s.end(err)
+	}
+
+	return err
+}
+
+// watch ends the stream once the context of the call is done, since a caller which cancels the call, e.g. to stop
+// reading a server stream early, may never receive the end of the stream
+func (s *clientStream) watch(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		s.end(status.FromContextError(ctx.Err()).Err())
+	case <-s.done:
+	}
+}
+
+// end logs the call the first time the stream ends
+func (s *clientStream) end(err error) {
+	s.once.Do(func() {
+		close(s.done)
+		s.finish(err)
+	})
+}
+
+func logClientCall(ctx context.Context, cfg *runtimeConfig, method string, cc *grpc.ClientConn, requestID string, t time.Time, err error) {
+	var target string
+	if cc != nil {
+		target = cc.Target()
+	}
+
+	level := cfg.clientLogLevel
+	if err != nil {
+		level = cfg.clientErrorLogLevel
+	}
+
+	withTracing(ctx, cfg, Fields{
+		"Method":       method,
+		"Target":       target,
+		"RequestID":    requestID,
+		"ResponseTime": time.Since(t).Seconds(),
+		"StatusCode":   status.Code(err).String(),
+	}, err).log(ctx, cfg, level, "grpc client call")
+}
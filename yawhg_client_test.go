@@ -0,0 +1,286 @@
+package yawhg_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/MarcvanMelle/yawhg"
+)
+
+type clientInterceptorTestCase struct {
+	name           string
+	context        context.Context
+	err            error
+	expectedResult []string
+}
+
+var clientInterceptorTestCases = []clientInterceptorTestCase{
+	clientInterceptorTestCase{
+		name:           "propagate_request_id_from_incoming_metadata",
+		context:        metadata.NewIncomingContext(context.Background(), metadata.Pairs(yawhg.RequestIDHeader, testRequestID)),
+		expectedResult: []string{`"Method":"/test.Service/Call"`, `"StatusCode":"OK"`, `"severity":"debug"`},
+	},
+	clientInterceptorTestCase{
+		name:           "keep_request_id_already_on_outgoing_metadata",
+		context:        yawhg.AddToContext(context.Background(), testRequestID),
+		expectedResult: []string{`"Method":"/test.Service/Call"`, `"StatusCode":"OK"`, `"severity":"debug"`},
+	},
+	clientInterceptorTestCase{
+		name:           "log_failed_calls_at_the_error_level",
+		context:        metadata.NewIncomingContext(context.Background(), metadata.Pairs(yawhg.RequestIDHeader, testRequestID)),
+		err:            status.Error(codes.NotFound, "missing"),
+		expectedResult: []string{`"StatusCode":"NotFound"`, `"severity":"error"`, `"Error":"rpc error: code = NotFound desc = missing"`},
+	},
+}
+
+func TestGRPCUnaryClientInterceptor(t *testing.T) {
+	for _, testCase := range clientInterceptorTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actualResult, restore := captureLogs(yawhg.Options{
+				Enabled:        true,
+				AppVersion:     "test",
+				LogLevel:       "DebugLevel",
+				ClientLogLevel: "DebugLevel",
+			})
+			defer restore()
+
+			invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				md, _ := metadata.FromOutgoingContext(ctx)
+				assert.Equal(t, []string{testRequestID}, md.Get(yawhg.RequestIDHeader))
+				return testCase.err
+			}
+
+			err := yawhg.GRPCUnaryClientInterceptor(testCase.context, "/test.Service/Call", nil, nil, nil, invoker)
+			assert.Equal(t, testCase.err, err)
+
+			for _, result := range testCase.expectedResult {
+				assert.Contains(t, actualResult.String(), result)
+			}
+		})
+	}
+}
+
+// blockingClientStream never receives the end of the stream
+type blockingClientStream struct {
+	grpc.ClientStream
+}
+
+func TestGRPCStreamClientInterceptorCanceled(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "InfoLevel"})
+	defer restore()
+
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return blockingClientStream{}, nil
+	}
+
+	ctx, cancel := context.WithCancel(yawhg.AddToContext(context.Background(), testRequestID))
+	_, err := yawhg.GRPCStreamClientInterceptor(ctx, &grpc.StreamDesc{ServerStreams: true}, nil, "/test.Service/Watch", streamer)
+	assert.NoError(t, err)
+	assert.Empty(t, actualResult.String())
+
+	cancel()
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(actualResult.String(), `"StatusCode":"Canceled"`)
+	}, time.Second, 10*time.Millisecond)
+	assert.Contains(t, actualResult.String(), `"Method":"/test.Service/Watch"`)
+	assert.Contains(t, actualResult.String(), `"severity":"error"`)
+}
+
+func TestTransport(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{
+		Enabled:          true,
+		AppVersion:       "test",
+		LogLevel:         "InfoLevel",
+		ClientLogHeaders: []string{"x-api-key", "Authorization", "Accept"},
+	})
+	defer restore()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, testRequestID, r.Header.Get(yawhg.RequestIDHeader))
+		w.WriteHeader(http.StatusTeapot)
+		// a flushed response is chunked, so that its length is unknown until it has been read
+		w.Write([]byte("short "))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("and stout"))
+	}))
+	defer server.Close()
+
+	ctx := yawhg.AddToContext(context.Background(), testRequestID)
+	ctx = yawhg.WithRedaction(ctx, yawhg.Redaction{QueryParams: []string{"token"}, Headers: []string{"X-Api-Key"}})
+
+	req, _ := http.NewRequest("GET", server.URL+"/path?token=secret&page=2", nil)
+	req.Header.Set("X-Api-Key", "secret")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Accept", "text/plain")
+	req.Header.Set("X-Session", "unlisted")
+
+	client := &http.Client{Transport: yawhg.Transport(nil)}
+	resp, err := client.Do(req.WithContext(ctx))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), resp.ContentLength)
+	assert.Empty(t, actualResult.String(), "the response is logged once its body has been read")
+
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "short and stout", string(body))
+	resp.Body.Close()
+
+	assert.Empty(t, req.Header.Get(yawhg.RequestIDHeader), "the original request must not be modified")
+	assert.NotContains(t, actualResult.String(), "secret")
+	assert.NotContains(t, actualResult.String(), "X-Session")
+	assert.Equal(t, 1, strings.Count(actualResult.String(), "\n"), "expected a single log line")
+
+	for _, result := range []string{
+		`"Method":"GET"`,
+		`"RequestPath":"/path"`,
+		`"RequestQuery":"page=2\u0026token=REDACTED"`,
+		`"X-Api-Key":"REDACTED"`,
+		`"Authorization":"REDACTED"`,
+		`"Accept":"text/plain"`,
+		`"Status":418`,
+		`"ResponseBytes":15`,
+		`"request_id":"` + testRequestID + `"`,
+	} {
+		assert.Contains(t, actualResult.String(), result)
+	}
+}
+
+func TestTransportUnreadBody(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "InfoLevel"})
+	defer restore()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ignored"))
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("Accept", "text/plain")
+
+	resp, err := (&http.Client{Transport: yawhg.Transport(nil)}).Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Contains(t, actualResult.String(), `"Status":200`)
+	assert.Contains(t, actualResult.String(), `"ResponseBytes":0`)
+	assert.NotContains(t, actualResult.String(), "RequestHeaders", "no header is logged by default")
+}
+
+type levelOverrideForwardingTestCase struct {
+	name            string
+	hosts           []string
+	expectedForward bool
+}
+
+var levelOverrideForwardingTestCases = []levelOverrideForwardingTestCase{
+	levelOverrideForwardingTestCase{
+		name: "do_not_forward_the_override_by_default",
+	},
+	levelOverrideForwardingTestCase{
+		name:  "do_not_forward_the_override_to_other_hosts",
+		hosts: []string{"billing.internal"},
+	},
+	levelOverrideForwardingTestCase{
+		name:            "forward_the_override_to_allowed_hosts",
+		hosts:           []string{"127.0.0.1"},
+		expectedForward: true,
+	},
+}
+
+func TestTransportLevelOverride(t *testing.T) {
+	for _, testCase := range levelOverrideForwardingTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actualResult, restore := captureLogs(yawhg.Options{
+				Enabled:             true,
+				AppVersion:          "test",
+				LogLevel:            "InfoLevel",
+				LevelOverrideSecret: testLevelOverrideSecret,
+				LevelOverrideHosts:  testCase.hosts,
+				ClientLogHeaders:    []string{yawhg.LevelOverrideHeader, yawhg.RequestIDHeader},
+			})
+			defer restore()
+
+			var forwarded string
+			downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				forwarded = r.Header.Get(yawhg.LevelOverrideHeader)
+			}))
+			defer downstream.Close()
+
+			handler := yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				req, _ := http.NewRequest("GET", downstream.URL, nil)
+				resp, err := (&http.Client{Transport: yawhg.Transport(nil)}).Do(req.WithContext(r.Context()))
+				if assert.NoError(t, err) {
+					resp.Body.Close()
+				}
+			}), yawhg.HTTPTraceMiddleware)
+
+			token := yawhg.SignLevelOverride(yawhg.DebugLevel, time.Now().Add(time.Minute), testLevelOverrideSecret)
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set(yawhg.LevelOverrideHeader, token)
+			req.Header.Set(yawhg.RequestIDHeader, testRequestID)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if testCase.expectedForward {
+				assert.Equal(t, token, forwarded)
+				assert.Contains(t, actualResult.String(), `"RequestHeaders":{"X-Yawhg-Level":"REDACTED"}`)
+			} else {
+				assert.Empty(t, forwarded)
+				assert.NotContains(t, actualResult.String(), "X-Yawhg-Level")
+			}
+			assert.NotContains(t, actualResult.String(), token)
+		})
+	}
+}
+
+func TestGRPCClientInterceptorLevelOverride(t *testing.T) {
+	for _, testCase := range levelOverrideForwardingTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, restore := captureLogs(yawhg.Options{
+				Enabled:             true,
+				AppVersion:          "test",
+				LogLevel:            "InfoLevel",
+				LevelOverrideSecret: testLevelOverrideSecret,
+				LevelOverrideHosts:  testCase.hosts,
+			})
+			defer restore()
+
+			// dialing does not block, so that the connection is never used
+			cc, err := grpc.Dial("dns:///127.0.0.1:50051", grpc.WithInsecure())
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer cc.Close()
+
+			token := yawhg.SignLevelOverride(yawhg.DebugLevel, time.Now().Add(time.Minute), testLevelOverrideSecret)
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(yawhg.LevelOverrideHeader, token))
+
+			var forwarded []string
+			_, err = yawhg.GRPCTraceInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, yawhg.GRPCUnaryClientInterceptor(ctx, "/test.Service/Call", nil, nil, cc, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+					md, _ := metadata.FromOutgoingContext(ctx)
+					forwarded = md.Get(yawhg.LevelOverrideHeader)
+					return nil
+				})
+			})
+
+			assert.NoError(t, err)
+			if testCase.expectedForward {
+				assert.Equal(t, []string{token}, forwarded)
+			} else {
+				assert.Empty(t, forwarded)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package yawhg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// loggerKey is the field holding the name of the component of a named logger
+const loggerKey string = "logger"
+
+// registeredLoggers holds the names of the named loggers created so far
+var registeredLoggers sync.Map
+
+// globalRule is the component of a level rule which sets the global level
+const globalRule string = "*"
+
+// Named returns a logger for a component of the application, e.g. yawhg.Named("billing.invoices").Info("message").
+// Its logs are written from the level set for the component or its closest parent, e.g. "billing", if any,
+// rather than the global level
+func Named(name string) *Fields {
+	registeredLoggers.LoadOrStore(name, struct{}{})
+	return &Fields{loggerKey: name}
+}
+
+// Loggers returns the names of the named loggers created so far, in alphabetical order
+func Loggers() []string {
+	names := []string{}
+	registeredLoggers.Range(func(name, _ interface{}) bool {
+		names = append(names, name.(string))
+		return true
+	})
+	sort.Strings(names)
+
+	return names
+}
+
+// SetComponentLevel sets the level from which the logs of the named loggers of a component are written
+func SetComponentLevel(name string, level Level) {
+	updateComponentLevels(func(levels map[string]Level) {
+		levels[name] = level
+	})
+	levelChanged(LevelChange{Component: name, Level: level.String()})
+}
+
+// ClearComponentLevel removes the level set for a component, whose logs are then written from the global level
+func ClearComponentLevel(name string) {
+	updateComponentLevels(func(levels map[string]Level) {
+		delete(levels, name)
+	})
+	levelChanged(LevelChange{Component: name})
+}
+
+// ComponentLevels returns the levels set for components
+func ComponentLevels() map[string]Level {
+	levels := map[string]Level{}
+	for name, level := range currentConfig().componentLevels {
+		levels[name] = level
+	}
+
+	return levels
+}
+
+// updateComponentLevels replaces the component levels with a modified copy, so that they can be read without locking
+func updateComponentLevels(fn func(levels map[string]Level)) {
+	updateConfig(func(cfg *runtimeConfig) {
+		levels := map[string]Level{}
+		for name, level := range cfg.componentLevels {
+			levels[name] = level
+		}
+		fn(levels)
+		cfg.componentLevels = levels
+	})
+}
+
+// componentLevel returns the level set for a component or its closest parent, if any
+func (cfg *runtimeConfig) componentLevel(name string) (Level, bool) {
+	levels := cfg.componentLevels
+	if len(levels) == 0 {
+		return 0, false
+	}
+
+	for {
+		if level, ok := levels[name]; ok {
+			return level, true
+		}
+
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			return 0, false
+		}
+		name = name[:i]
+	}
+}
+
+// ParseLevelRules parses comma separated level rules of components, such as "billing=debug,billing.pdf=error,*=info",
+// where "*" is the global level
+func ParseLevelRules(rules string) (map[string]Level, error) {
+	levels := map[string]Level{}
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("not a valid level rule: %q", rule)
+		}
+
+		level, err := ParseLevel(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+		levels[strings.TrimSpace(parts[0])] = level
+	}
+
+	return levels, nil
+}
+
+// SetLevelRules replaces the levels of all components with level rules, see ParseLevelRules.
+// A "*" rule sets the global level
+func SetLevelRules(rules string) error {
+	levels, err := ParseLevelRules(rules)
+	if err != nil {
+		return err
+	}
+
+	setLevelRules(levels)
+	return nil
+}
+
+func setLevelRules(levels map[string]Level) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	var changes []LevelChange
+	updateConfig(func(cfg *runtimeConfig) {
+		changes = cfg.applyLevelRules(levels)
+	})
+
+	for _, change := range changes {
+		levelChanged(change)
+	}
+}
+
+// applyLevelRules replaces the component levels of the configuration with the levels of rules, where a "*" rule
+// replaces the global level and any temporary level, and returns the changes of levels. levelMu must be held
+func (cfg *runtimeConfig) applyLevelRules(rules map[string]Level) []LevelChange {
+	var changes []LevelChange
+	levels := map[string]Level{}
+	for name, level := range rules {
+		if name == globalRule {
+			stopTemporaryLevel()
+			baseLogLevel = level
+			cfg.level = level
+			changes = append(changes, LevelChange{Level: level.String()})
+			continue
+		}
+		levels[name] = level
+	}
+
+	for name := range cfg.componentLevels {
+		if _, ok := levels[name]; !ok {
+			changes = append(changes, LevelChange{Component: name})
+		}
+	}
+	for name, level := range levels {
+		if previous, ok := cfg.componentLevels[name]; !ok || previous != level {
+			changes = append(changes, LevelChange{Component: name, Level: level.String()})
+		}
+	}
+	cfg.componentLevels = levels
+
+	return changes
+}
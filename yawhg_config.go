@@ -0,0 +1,387 @@
+package yawhg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultEnvPrefix is the prefix of the environment variables read by ConfigFromEnv when none is given
+const defaultEnvPrefix string = "YAWHG"
+
+// requestIDGenerators and requestIDValidators are the values of the request_id_generator and request_id_validator
+// settings
+var requestIDGenerators = map[string]RequestIDGenerator{
+	"uuidv4": NewUUIDv4,
+	"uuidv7": NewUUIDv7,
+	"ulid":   NewULID,
+	"ksuid":  NewKSUID,
+}
+
+var requestIDValidators = map[string]RequestIDValidator{
+	"default": ValidRequestID,
+	"none":    func(string) bool { return true },
+}
+
+// outputMu guards the log file opened for the output setting, which is closed once replaced
+var outputMu sync.Mutex
+var outputFile *os.File
+
+// config holds the settings of a config file, or of environment variables named after the yaml keys in uppercase,
+// e.g. YAWHG_APP_VERSION. Lists are comma separated in environment variables, and other structures are JSON
+type config struct {
+	AppVersion            string         `yaml:"app_version" json:"app_version"`
+	Enabled               *bool          `yaml:"enabled" json:"enabled"`
+	Output                string         `yaml:"output" json:"output"`
+	Level                 string         `yaml:"level" json:"level"`
+	LogStreamMessages     bool           `yaml:"log_stream_messages" json:"log_stream_messages"`
+	ClientLevel           string         `yaml:"client_level" json:"client_level"`
+	ClientErrorLevel      string         `yaml:"client_error_level" json:"client_error_level"`
+	ClientLogHeaders      []string       `yaml:"client_log_headers" json:"client_log_headers"`
+	MaxBodyLogSize        int            `yaml:"max_body_log_size" json:"max_body_log_size"`
+	BodyLogSkipPaths      []string       `yaml:"body_log_skip_paths" json:"body_log_skip_paths"`
+	RedactedFormFields    []string       `yaml:"redacted_form_fields" json:"redacted_form_fields"`
+	ExtractFormats        []string       `yaml:"extract_formats" json:"extract_formats"`
+	InjectFormats         []string       `yaml:"inject_formats" json:"inject_formats"`
+	RecordErrorSpanEvents bool           `yaml:"record_error_span_events" json:"record_error_span_events"`
+	BufferRequestLogs     bool           `yaml:"buffer_request_logs" json:"buffer_request_logs"`
+	RequestBufferSize     int            `yaml:"request_buffer_size" json:"request_buffer_size"`
+	LevelOverrideSecret   string         `yaml:"level_override_secret" json:"level_override_secret"`
+	LevelOverrideHosts    []string       `yaml:"level_override_hosts" json:"level_override_hosts"`
+	LevelRules            string         `yaml:"level_rules" json:"level_rules"`
+	SamplingRules         []SamplingRule `yaml:"sampling_rules" json:"sampling_rules"`
+	SamplingInterval      string         `yaml:"sampling_interval" json:"sampling_interval"`
+	SampleByRequestID     bool           `yaml:"sample_by_request_id" json:"sample_by_request_id"`
+	DedupWindow           string         `yaml:"dedup_window" json:"dedup_window"`
+	DedupKeys             []string       `yaml:"dedup_keys" json:"dedup_keys"`
+	DedupMaxEntries       int            `yaml:"dedup_max_entries" json:"dedup_max_entries"`
+	RequestIDHeader       string         `yaml:"request_id_header" json:"request_id_header"`
+	RequestIDGenerator    string         `yaml:"request_id_generator" json:"request_id_generator"`
+	RequestIDValidator    string         `yaml:"request_id_validator" json:"request_id_validator"`
+}
+
+// ConfigFromEnv configures yawhg from environment variables with the prefix, "YAWHG" by default, such as YAWHG_LEVEL,
+// YAWHG_ENABLED, YAWHG_APP_VERSION and YAWHG_OUTPUT, which is stdout, stderr or the path of a log file.
+// It returns an error describing every invalid setting, in which case the configuration is left unchanged
+func ConfigFromEnv(prefix string) error {
+	c, err := envConfig(prefix)
+	if err != nil {
+		return err
+	}
+
+	return c.apply()
+}
+
+// ConfigFromFile configures yawhg from a YAML or JSON file, whose keys are the names of the environment variables of
+// ConfigFromEnv in lowercase without the prefix, e.g. level and app_version. Unknown keys are rejected.
+// It returns an error describing every invalid setting, in which case the configuration is left unchanged
+func ConfigFromFile(path string) error {
+	c, err := fileConfig(path)
+	if err != nil {
+		return err
+	}
+
+	return c.apply()
+}
+
+func envConfig(prefix string) (config, error) {
+	if prefix == "" {
+		prefix = defaultEnvPrefix
+	}
+	prefix = strings.TrimSuffix(prefix, "_") + "_"
+
+	var c config
+	var errs []string
+	v := reflect.ValueOf(&c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := prefix + strings.ToUpper(v.Type().Field(i).Tag.Get("yaml"))
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		if err := setEnvField(v.Field(i), value); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return c, fmt.Errorf("invalid yawhg environment: %s", strings.Join(errs, "; "))
+	}
+
+	return c, nil
+}
+
+// setEnvField sets a field of the config from the value of its environment variable
+func setEnvField(field reflect.Value, value string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(value)
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("not a valid boolean: %q", value)
+		}
+		field.SetBool(b)
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("not a valid boolean: %q", value)
+		}
+		field.Set(reflect.ValueOf(&b))
+	case int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("not a valid integer: %q", value)
+		}
+		field.SetInt(int64(n))
+	case []string:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		field.Set(reflect.ValueOf(list))
+	default:
+		// settings which are not scalars or lists are JSON, e.g. YAWHG_SAMPLING_RULES='[{"level":"info","first":10}]'
+		if err := json.Unmarshal([]byte(value), field.Addr().Interface()); err != nil {
+			return fmt.Errorf("not valid JSON: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func fileConfig(path string) (config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config{}, fmt.Errorf("reading yawhg config: %v", err)
+	}
+
+	return parseConfig(path, data)
+}
+
+// parseConfig parses the content of a config file, whose format depends on the extension of its path
+func parseConfig(path string, data []byte) (config, error) {
+	var c config
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&c)
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&c)
+		if err == io.EOF {
+			err = nil // an empty file keeps every default
+		}
+	default:
+		return c, fmt.Errorf("reading yawhg config %s: not a .yaml, .yml or .json file", path)
+	}
+
+	if err != nil {
+		return c, fmt.Errorf("parsing yawhg config %s: %v", path, err)
+	}
+
+	return c, nil
+}
+
+// options converts the settings to options, opening the log file of the output setting if any
+func (c config) options() (Options, *os.File, error) {
+	options := Options{
+		AppVersion:            c.AppVersion,
+		Enabled:               c.Enabled == nil || *c.Enabled,
+		LogLevel:              c.Level,
+		LogStreamMessages:     c.LogStreamMessages,
+		ClientLogLevel:        c.ClientLevel,
+		ClientErrorLogLevel:   c.ClientErrorLevel,
+		ClientLogHeaders:      c.ClientLogHeaders,
+		MaxBodyLogSize:        c.MaxBodyLogSize,
+		BodyLogSkipPaths:      c.BodyLogSkipPaths,
+		RedactedFormFields:    c.RedactedFormFields,
+		ExtractFormats:        c.ExtractFormats,
+		InjectFormats:         c.InjectFormats,
+		RecordErrorSpanEvents: c.RecordErrorSpanEvents,
+		BufferRequestLogs:     c.BufferRequestLogs,
+		RequestBufferSize:     c.RequestBufferSize,
+		LevelOverrideSecret:   c.LevelOverrideSecret,
+		LevelOverrideHosts:    c.LevelOverrideHosts,
+		LevelRules:            c.LevelRules,
+		SamplingRules:         c.SamplingRules,
+		SampleByRequestID:     c.SampleByRequestID,
+		DedupKeys:             c.DedupKeys,
+		DedupMaxEntries:       c.DedupMaxEntries,
+		RequestIDHeader:       c.RequestIDHeader,
+	}
+
+	var errs []string
+	if c.RequestIDGenerator != "" {
+		if options.RequestIDGenerator = requestIDGenerators[strings.ToLower(c.RequestIDGenerator)]; options.RequestIDGenerator == nil {
+			errs = append(errs, fmt.Sprintf("request_id_generator: not a valid generator: %q", c.RequestIDGenerator))
+		}
+	}
+	if c.RequestIDValidator != "" {
+		if options.RequestIDValidator = requestIDValidators[strings.ToLower(c.RequestIDValidator)]; options.RequestIDValidator == nil {
+			errs = append(errs, fmt.Sprintf("request_id_validator: not a valid validator: %q", c.RequestIDValidator))
+		}
+	}
+	if c.SamplingInterval != "" {
+		interval, err := time.ParseDuration(c.SamplingInterval)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("sampling_interval: not a valid duration: %q", c.SamplingInterval))
+		}
+		options.SamplingInterval = interval
+	}
+	if c.DedupWindow != "" {
+		window, err := time.ParseDuration(c.DedupWindow)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("dedup_window: not a valid duration: %q", c.DedupWindow))
+		}
+		options.DedupWindow = window
+	}
+	if err := options.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return options, nil, fmt.Errorf("invalid yawhg config: %s", strings.Join(errs, "; "))
+	}
+
+	var file *os.File
+	switch c.Output {
+	case "", "stdout":
+		options.Output = os.Stdout
+	case "stderr":
+		options.Output = os.Stderr
+	default:
+		var err error
+		file, err = os.OpenFile(c.Output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return options, nil, fmt.Errorf("invalid yawhg config: output: %v", err)
+		}
+		options.Output = file
+	}
+
+	return options, file, nil
+}
+
+// apply configures yawhg with the settings, and closes the log file of the previous settings
+func (c config) apply() error {
+	options, file, err := c.options()
+	if err != nil {
+		return err
+	}
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	ConfigYawhg(options)
+
+	if outputFile != nil {
+		outputFile.Close()
+	}
+	outputFile = file
+
+	return nil
+}
+
+// Validate returns an error describing every invalid option, which ConfigYawhg would otherwise replace with its default
+func (options Options) Validate() error {
+	var errs []string
+	for name, level := range map[string]string{
+		"LogLevel":            options.LogLevel,
+		"ClientLogLevel":      options.ClientLogLevel,
+		"ClientErrorLogLevel": options.ClientErrorLogLevel,
+	} {
+		if level == "" {
+			continue
+		}
+		if _, err := parseOptionLevel(level); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+
+	if options.MaxBodyLogSize < 0 {
+		errs = append(errs, fmt.Sprintf("MaxBodyLogSize: must not be negative, got %d", options.MaxBodyLogSize))
+	}
+	if options.RequestBufferSize < 0 {
+		errs = append(errs, fmt.Sprintf("RequestBufferSize: must not be negative, got %d", options.RequestBufferSize))
+	}
+
+	for name, formats := range map[string][]string{
+		"ExtractFormats": options.ExtractFormats,
+		"InjectFormats":  options.InjectFormats,
+	} {
+		for _, format := range formats {
+			if _, ok := propagators[format]; !ok && format != RequestIDFormat {
+				errs = append(errs, fmt.Sprintf("%s: not a valid format: %q", name, format))
+			}
+		}
+	}
+
+	for i, rule := range options.SamplingRules {
+		if rule.Level != "" {
+			if _, err := parseOptionLevel(rule.Level); err != nil {
+				errs = append(errs, fmt.Sprintf("SamplingRules[%d]: %v", i, err))
+			}
+		}
+		if rule.First < 0 || rule.Thereafter < 0 {
+			errs = append(errs, fmt.Sprintf("SamplingRules[%d]: First and Thereafter must not be negative", i))
+		}
+	}
+	if options.SamplingInterval < 0 {
+		errs = append(errs, fmt.Sprintf("SamplingInterval: must not be negative, got %s", options.SamplingInterval))
+	}
+
+	if options.DedupWindow < 0 {
+		errs = append(errs, fmt.Sprintf("DedupWindow: must not be negative, got %s", options.DedupWindow))
+	}
+	if options.DedupMaxEntries < 0 {
+		errs = append(errs, fmt.Sprintf("DedupMaxEntries: must not be negative, got %d", options.DedupMaxEntries))
+	}
+
+	if _, err := ParseLevelRules(options.LevelRules); err != nil {
+		errs = append(errs, fmt.Sprintf("LevelRules: %v", err))
+	}
+
+	if options.RequestIDHeader != "" && !validMetadataKey(strings.ToLower(options.RequestIDHeader)) {
+		errs = append(errs, fmt.Sprintf("RequestIDHeader: not a valid header and metadata key: %q", options.RequestIDHeader))
+	}
+
+	if len(errs) > 0 {
+		// the options of maps are checked in random order
+		sort.Strings(errs)
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// validMetadataKey reports whether the key is allowed in gRPC metadata, which excludes the keys reserved by gRPC
+func validMetadataKey(key string) bool {
+	if strings.HasPrefix(key, "grpc-") {
+		return false
+	}
+
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,289 @@
+package yawhg_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MarcvanMelle/yawhg"
+)
+
+var configFileTestCases = []struct {
+	name          string
+	file          string
+	content       string
+	expectedLevel yawhg.Level
+	expectedError string
+}{
+	{
+		name:          "configure_from_yaml",
+		file:          "yawhg.yaml",
+		content:       "level: debug\napp_version: test\nextract_formats: [w3c, b3]\nrequest_id_generator: ulid\n",
+		expectedLevel: yawhg.DebugLevel,
+	},
+	{
+		name:          "configure_from_json",
+		file:          "yawhg.json",
+		content:       `{"level": "ErrorLevel", "level_rules": "billing=debug"}`,
+		expectedLevel: yawhg.ErrorLevel,
+	},
+	{
+		name:          "reject_unknown_keys",
+		file:          "yawhg.yaml",
+		content:       "levle: debug\n",
+		expectedLevel: yawhg.InfoLevel,
+		expectedError: "field levle not found",
+	},
+	{
+		name:          "reject_invalid_settings",
+		file:          "yawhg.json",
+		content:       `{"level": "verbose", "inject_formats": ["jaeger"], "request_id_generator": "serial"}`,
+		expectedLevel: yawhg.InfoLevel,
+		expectedError: `invalid yawhg config: request_id_generator: not a valid generator: "serial"; InjectFormats: not a valid format: "jaeger"; LogLevel: not a valid Level: "verbose"`,
+	},
+	{
+		name:          "reject_other_file_types",
+		file:          "yawhg.toml",
+		content:       "level = \"debug\"\n",
+		expectedLevel: yawhg.InfoLevel,
+		expectedError: "not a .yaml, .yml or .json file",
+	},
+}
+
+func TestConfigFromFile(t *testing.T) {
+	for _, testCase := range configFileTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			yawhg.ConfigYawhg(yawhg.Options{Enabled: false, LogLevel: "InfoLevel"})
+			defer yawhg.ConfigYawhg(yawhg.Options{Enabled: true, LogLevel: "InfoLevel"})
+
+			dir, err := ioutil.TempDir("", "yawhg")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, testCase.file)
+			require.NoError(t, ioutil.WriteFile(path, []byte(testCase.content), 0644))
+
+			err = yawhg.ConfigFromFile(path)
+			if testCase.expectedError != "" {
+				assert.Contains(t, err.Error(), testCase.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, testCase.expectedLevel, yawhg.GetLevel())
+		})
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	previousDestination := yawhg.Destination
+	defer func() { yawhg.Destination = previousDestination }()
+	defer yawhg.ConfigYawhg(yawhg.Options{Enabled: true, LogLevel: "InfoLevel"})
+
+	dir, err := ioutil.TempDir("", "yawhg")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	env := map[string]string{
+		"APP_LEVEL":       "debug",
+		"APP_APP_VERSION": "1.2.3",
+		"APP_OUTPUT":      filepath.Join(dir, "app.log"),
+		"APP_LEVEL_RULES": "billing=error",
+	}
+	for name, value := range env {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	require.NoError(t, yawhg.ConfigFromEnv("APP"))
+	assert.Equal(t, yawhg.DebugLevel, yawhg.GetLevel())
+	assert.Equal(t, map[string]yawhg.Level{"billing": yawhg.ErrorLevel}, yawhg.ComponentLevels())
+
+	yawhg.Debugf("written to the log file")
+	logs, err := ioutil.ReadFile(env["APP_OUTPUT"])
+	require.NoError(t, err)
+	assert.Contains(t, string(logs), `"msg":"written to the log file"`)
+	assert.Contains(t, string(logs), `"v":"1.2.3"`)
+
+	os.Setenv("APP_ENABLED", "sometimes")
+	defer os.Unsetenv("APP_ENABLED")
+	assert.EqualError(t, yawhg.ConfigFromEnv("APP"), `invalid yawhg environment: APP_ENABLED: not a valid boolean: "sometimes"`)
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	defer yawhg.ConfigYawhg(yawhg.Options{Enabled: true, LogLevel: "InfoLevel"})
+
+	dir, err := ioutil.TempDir("", "yawhg")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "yawhg.yaml")
+	output := filepath.Join(dir, "app.log")
+	// the config is replaced atomically, so that the watcher never reads a partially written file
+	writeConfig := func(content string) {
+		require.NoError(t, ioutil.WriteFile(path+".tmp", []byte("output: "+output+"\n"+content), 0644))
+		require.NoError(t, os.Rename(path+".tmp", path))
+	}
+
+	writeConfig("level: info\n")
+	stop, err := yawhg.WatchConfigFile(path, 10*time.Millisecond)
+	require.NoError(t, err)
+	defer stop()
+
+	writeConfig("level: debug\n")
+	assert.Eventually(t, func() bool { return yawhg.GetLevel() == yawhg.DebugLevel }, time.Second, 10*time.Millisecond)
+
+	writeConfig("level: verbose\n")
+	assert.Eventually(t, func() bool {
+		logs, _ := ioutil.ReadFile(output)
+		return strings.Contains(string(logs), `"msg":"reloading yawhg config"`)
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, yawhg.DebugLevel, yawhg.GetLevel())
+
+	// SIGHUP reloads the file without waiting for the next check
+	writeConfig("level: error\n")
+	process, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, process.Signal(syscall.SIGHUP))
+	assert.Eventually(t, func() bool { return yawhg.GetLevel() == yawhg.ErrorLevel }, time.Second, 10*time.Millisecond)
+
+	logs, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Contains(t, string(logs), `"Level":"debug","Path":"`+path+`","msg":"yawhg config reloaded"`)
+	assert.Contains(t, string(logs), `"Error":"invalid yawhg config: LogLevel: not a valid Level: \"verbose\""`)
+}
+
+func TestConfigYawhgWhileServing(t *testing.T) {
+	output := new(syncBuffer)
+	configs := []yawhg.Options{
+		{Enabled: true, Output: output, AppVersion: "v1", LogLevel: "InfoLevel"},
+		{
+			Enabled:             true,
+			Output:              output,
+			AppVersion:          "v2",
+			LogLevel:            "DebugLevel",
+			RequestIDHeader:     "x-correlation-id",
+			ExtractFormats:      []string{yawhg.RequestIDFormat, yawhg.B3Format},
+			InjectFormats:       []string{yawhg.B3Format},
+			MaxBodyLogSize:      8,
+			BufferRequestLogs:   true,
+			LevelOverrideSecret: testLevelOverrideSecret,
+			ClientLogLevel:      "DebugLevel",
+			DedupWindow:         time.Millisecond,
+			SamplingRules:       []yawhg.SamplingRule{{Level: "debug", First: 10}},
+			ErrorHandler:        func(error) {},
+		},
+	}
+	defer yawhg.ConfigYawhg(yawhg.Options{Enabled: false, LogLevel: "InfoLevel"})
+	yawhg.ConfigYawhg(configs[0])
+
+	handler := yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		yawhg.InfoWithTracing(r.Context(), yawhg.Fields{"msg": "handling"})
+		yawhg.Debugft(r.Context(), "handling %s", r.URL.Path)
+	}), yawhg.HTTPLogMiddleware, yawhg.HTTPTraceMiddleware)
+
+	done := make(chan struct{})
+	reloaded := make(chan struct{})
+	go func() {
+		defer close(reloaded)
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+				yawhg.ConfigYawhg(configs[i%len(configs)])
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				req := httptest.NewRequest("POST", "/orders", strings.NewReader(`{"id":"order"}`))
+				req.Header.Set(yawhg.RequestIDHeader, testRequestID)
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+				yawhg.Infoft(context.Background(), "between requests")
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	<-reloaded
+
+	// every log is written whole, with either configuration
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		var logged map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &logged), line)
+		assert.Contains(t, []interface{}{"v1", "v2"}, logged["v"], line)
+	}
+}
+
+func TestConfigYawhgLevelWhileLogging(t *testing.T) {
+	output := new(syncBuffer)
+	// the debug level of the first configuration is overridden by its global rule, so that no configuration writes
+	// debug logs unless its level and rules are mixed
+	configs := []yawhg.Options{
+		{Enabled: true, Output: output, LogLevel: "DebugLevel", LevelRules: "*=error"},
+		{Enabled: true, Output: output, LogLevel: "InfoLevel"},
+	}
+	defer yawhg.ConfigYawhg(yawhg.Options{Enabled: false, LogLevel: "InfoLevel"})
+	yawhg.ConfigYawhg(configs[0])
+
+	done := make(chan struct{})
+	logged := make(chan struct{})
+	go func() {
+		defer close(logged)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				yawhg.Debug("between configurations")
+			}
+		}
+	}()
+
+	for i := 0; i < 1000; i++ {
+		yawhg.ConfigYawhg(configs[i%len(configs)])
+	}
+	close(done)
+	<-logged
+
+	assert.NotContains(t, output.String(), "between configurations")
+}
+
+func TestConfigYawhgInvalidOptions(t *testing.T) {
+	var handled []string
+	_, restore := captureLogs(yawhg.Options{
+		Enabled:    true,
+		AppVersion: "test",
+		LogLevel:   "LoudLevel",
+		LevelRules: "billing=debug,pdf=loud",
+		ErrorHandler: func(err error) {
+			handled = append(handled, err.Error())
+		},
+	})
+	defer restore()
+
+	// the invalid options are replaced with their defaults, and reported once
+	assert.Equal(t, yawhg.InfoLevel, yawhg.GetLevel())
+	assert.Empty(t, yawhg.ComponentLevels())
+	if assert.Len(t, handled, 1) {
+		assert.Contains(t, handled[0], "LogLevel")
+		assert.Contains(t, handled[0], "LevelRules")
+	}
+}
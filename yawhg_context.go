@@ -4,51 +4,106 @@ import (
 	"context"
 	"net/http"
 
-	"github.com/gofrs/uuid"
 	"google.golang.org/grpc/metadata"
 )
 
 // AddToContext attaches the request ID header to context metadata as a key/value pair
 func AddToContext(ctx context.Context, requestID string) context.Context {
-	return metadata.AppendToOutgoingContext(ctx, RequestIDHeader, requestID)
+	return addToContext(ctx, currentConfig(), requestID)
+}
+
+func addToContext(ctx context.Context, cfg *runtimeConfig, requestID string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, cfg.requestIDHeader, requestID)
 }
 
 // AddToHeader is a helper function that adds a request ID to the x-request-id http header
 func AddToHeader(r *http.Request, requestID string) (context.Context, *http.Request) {
+	return addToHeader(r, currentConfig(), requestID)
+}
+
+func addToHeader(r *http.Request, cfg *runtimeConfig, requestID string) (context.Context, *http.Request) {
 	// https://godoc.org/net/http#Handler - "Except for reading the body, handlers should not modify the provided Request."
 	// We create a shallow copy of the request, update the copy, and return that
 	r2 := new(http.Request)
 	*r2 = *r
-	r2.Header.Set(RequestIDHeader, requestID) // add or replace request id header
+	r2.Header.Set(cfg.requestIDHeader, requestID) // add or replace request id header
 
 	ctx := r2.Context()
-	return AddToContext(ctx, requestID), r2
+	return addToContext(ctx, cfg, requestID), r2
 }
 
 // FromContext retrieves the request id from the context if it exists.
-// It will generate a new request id if the context has none, and append it to the context
+// It will generate a new request id if the context has none, or an invalid one, and append it to the context.
+// A context without a span whose incoming metadata carries a parent span in one of the Options.ExtractFormats is given
+// a child of that span, see SpanFromContext
 func FromContext(ctx context.Context) (context.Context, string) {
+	return fromContext(ctx, currentConfig())
+}
+
+func fromContext(ctx context.Context, cfg *runtimeConfig) (context.Context, string) {
+	ctx = addIncomingSpan(ctx, cfg)
+
 	// check if request ID is stored in the incomingKey
 	md, ok := metadata.FromIncomingContext(ctx)
-	if ok && len(md.Get(RequestIDHeader)) > 0 {
-		return ctx, md.Get(RequestIDHeader)[0]
+	if ok && len(md.Get(cfg.requestIDHeader)) > 0 && hasFormat(cfg.extractFormats, RequestIDFormat) && cfg.validateRequestID(md.Get(cfg.requestIDHeader)[0]) {
+		return ctx, md.Get(cfg.requestIDHeader)[0]
 	}
 
 	// check if request ID is stored in the outgoingKey
 	md, ok = metadata.FromOutgoingContext(ctx)
-	if ok && len(md.Get(RequestIDHeader)) > 0 {
-		return ctx, md.Get(RequestIDHeader)[0]
+	if ok && len(md.Get(cfg.requestIDHeader)) > 0 {
+		return ctx, md.Get(cfg.requestIDHeader)[0]
 	}
 
 	// no request ID found, append to context metadata and return the new context
-	newUUID, _ := uuid.NewV4()
+	requestID, _ := cfg.generateRequestID() // generate new request ID if not present
+	ctx = addToContext(ctx, cfg, requestID)
+	return ctx, requestID
+}
+
+// outgoingRequestID retrieves the request id and span from the context, and ensures that they are attached to the
+// outgoing metadata so that they are propagated to downstream services
+func outgoingRequestID(ctx context.Context, cfg *runtimeConfig) (context.Context, string) {
+	ctx = addIncomingSpan(ctx, cfg)
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if sc, active := SpanFromContext(ctx); active {
+		// the active OpenTelemetry span may be a child of the span attached by the trace interceptors
+		if propagated, found := extractSpan(mdCarrier(md), cfg.injectFormats); !found || propagated.SpanID != sc.SpanID {
+			ctx = contextWithSpan(ctx, cfg, sc)
+			md, ok = metadata.FromOutgoingContext(ctx)
+		}
+	}
+
+	if ok && len(md.Get(cfg.requestIDHeader)) > 0 {
+		return ctx, md.Get(cfg.requestIDHeader)[0]
+	}
+
+	ctx, requestID := fromContext(ctx, cfg)
+
+	// a request ID found in the incoming metadata must be copied to the outgoing metadata
+	md, ok = metadata.FromOutgoingContext(ctx)
+	if !ok || len(md.Get(cfg.requestIDHeader)) == 0 {
+		ctx = addToContext(ctx, cfg, requestID)
+	}
 
-	requestID := newUUID.String() // generate new request ID if not present
-	ctx = AddToContext(ctx, requestID)
 	return ctx, requestID
 }
 
+// callContext returns the context for an outgoing call, whose metadata only carries the request ID if it is one of the
+// Options.InjectFormats. The request ID otherwise remains in the outgoing metadata of the context for logging purposes
+func callContext(ctx context.Context, cfg *runtimeConfig) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok || hasFormat(cfg.injectFormats, RequestIDFormat) {
+		return ctx
+	}
+
+	md = md.Copy()
+	delete(md, cfg.requestIDHeader)
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
 // FromHeader retrieves the value of the x-request-id header
 func FromHeader(req *http.Request) string {
-	return req.Header.Get(RequestIDHeader)
+	return req.Header.Get(currentConfig().requestIDHeader)
 }
@@ -5,10 +5,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/MarcvanMelle/yawhg"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -77,3 +82,368 @@ func TestRequestID(t *testing.T) {
 		})
 	}
 }
+
+const testTraceID string = "4bf92f3577b34da6a3ce929d0e0e4736"
+const testSpanID string = "00f067aa0ba902b7"
+
+type spanContextTestCase struct {
+	name            string
+	context         context.Context
+	expectedTraceID string
+	expectedParent  string
+	expectedSampled bool
+}
+
+var spanContextTestCases = []spanContextTestCase{
+	spanContextTestCase{
+		name: "start_child_span_of_incoming_traceparent",
+		context: metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+			yawhg.TraceparentHeader, "00-"+testTraceID+"-"+testSpanID+"-01",
+			yawhg.TracestateHeader, "vendor=value",
+		)),
+		expectedTraceID: testTraceID,
+		expectedParent:  testSpanID,
+		expectedSampled: true,
+	},
+	spanContextTestCase{
+		name: "honour_sampled_flag_of_incoming_traceparent",
+		context: metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+			yawhg.TraceparentHeader, "00-"+testTraceID+"-"+testSpanID+"-00",
+		)),
+		expectedTraceID: testTraceID,
+		expectedParent:  testSpanID,
+		expectedSampled: false,
+	},
+	spanContextTestCase{
+		name: "start_new_trace_for_invalid_traceparent",
+		context: metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+			yawhg.TraceparentHeader, "00-"+strings.Repeat("0", 32)+"-"+testSpanID+"-01",
+		)),
+		expectedSampled: true,
+	},
+	spanContextTestCase{
+		name:            "start_new_trace_without_traceparent",
+		context:         context.Background(),
+		expectedSampled: true,
+	},
+}
+
+func TestSpanContext(t *testing.T) {
+	for _, testCase := range spanContextTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var ctx context.Context
+			_, err := yawhg.GRPCTraceInterceptor(testCase.context, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}, func(handlerCtx context.Context, req interface{}) (interface{}, error) {
+				ctx = handlerCtx
+				return nil, nil
+			})
+			assert.NoError(t, err)
+
+			sc, ok := yawhg.SpanFromContext(ctx)
+			assert.True(t, ok)
+			assert.Regexp(t, regexp.MustCompile("^[a-f0-9]{32}$"), sc.TraceID)
+			assert.Regexp(t, regexp.MustCompile("^[a-f0-9]{16}$"), sc.SpanID)
+			assert.NotEqual(t, testSpanID, sc.SpanID)
+			assert.Equal(t, testCase.expectedParent, sc.ParentSpanID)
+			assert.Equal(t, testCase.expectedSampled, sc.Sampled)
+			if testCase.expectedTraceID != "" {
+				assert.Equal(t, testCase.expectedTraceID, sc.TraceID)
+			}
+
+			// the current span is propagated as the parent of downstream services
+			md, _ := metadata.FromOutgoingContext(ctx)
+			flags := "00"
+			if sc.Sampled {
+				flags = "01"
+			}
+			assert.Equal(t, []string{"00-" + sc.TraceID + "-" + sc.SpanID + "-" + flags}, md.Get(yawhg.TraceparentHeader))
+
+			// the span is stable for the remainder of the request
+			_, reqID := yawhg.FromContext(ctx)
+			ctx, sameReqID := yawhg.FromContext(ctx)
+			assert.Equal(t, reqID, sameReqID)
+			same, _ := yawhg.SpanFromContext(ctx)
+			assert.Equal(t, sc, same)
+		})
+	}
+}
+
+func TestSpanPerCall(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "InfoLevel"})
+	defer restore()
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		yawhg.TraceparentHeader, "00-"+testTraceID+"-"+testSpanID+"-01",
+	))
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}
+	_, err := yawhg.GRPCTraceInterceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return yawhg.GRPCLogInterceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			yawhg.Infoft(ctx, "handled")
+			return nil, nil
+		})
+	})
+	assert.NoError(t, err)
+
+	// the request, handler and response logs of the call share its span
+	spanIDs := regexp.MustCompile(`"span_id":"([a-f0-9]{16})"`).FindAllStringSubmatch(actualResult.String(), -1)
+	if assert.Len(t, spanIDs, 3) {
+		assert.Equal(t, spanIDs[0][1], spanIDs[1][1])
+		assert.Equal(t, spanIDs[0][1], spanIDs[2][1])
+		assert.NotEqual(t, testSpanID, spanIDs[0][1])
+	}
+	assert.Equal(t, 3, strings.Count(actualResult.String(), `"trace_id":"`+testTraceID+`"`))
+}
+
+type fromContextSpanTestCase struct {
+	name            string
+	extractFormats  []string
+	context         context.Context
+	expectedTraceID string
+}
+
+var fromContextSpanTestCases = []fromContextSpanTestCase{
+	fromContextSpanTestCase{
+		name: "extract_incoming_traceparent",
+		context: metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+			yawhg.RequestIDHeader, testRequestID,
+			yawhg.TraceparentHeader, "00-"+testTraceID+"-"+testSpanID+"-01",
+		)),
+		expectedTraceID: testTraceID,
+	},
+	fromContextSpanTestCase{
+		name:           "extract_incoming_b3",
+		extractFormats: []string{yawhg.RequestIDFormat, yawhg.B3Format},
+		context: metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+			yawhg.RequestIDHeader, testRequestID,
+			yawhg.B3Header, testTraceID+"-"+testSpanID+"-1",
+		)),
+		expectedTraceID: testTraceID,
+	},
+	fromContextSpanTestCase{
+		name:    "no_span_without_incoming_trace",
+		context: yawhg.AddToContext(context.Background(), testRequestID),
+	},
+}
+
+func TestFromContextSpan(t *testing.T) {
+	for _, testCase := range fromContextSpanTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actualResult, restore := captureLogs(yawhg.Options{
+				Enabled:        true,
+				AppVersion:     "test",
+				LogLevel:       "InfoLevel",
+				ExtractFormats: testCase.extractFormats,
+			})
+			defer restore()
+
+			yawhg.Info("plain")
+			ctx, _ := yawhg.FromContext(testCase.context)
+			yawhg.Infoft(ctx, "first")
+			yawhg.Infoft(ctx, "second")
+
+			assert.Equal(t, 2, strings.Count(actualResult.String(), `"request_id":"`+testRequestID+`"`))
+
+			sc, ok := yawhg.SpanFromContext(ctx)
+			if testCase.expectedTraceID == "" {
+				assert.False(t, ok)
+				assert.NotContains(t, actualResult.String(), "trace_id")
+				assert.NotContains(t, actualResult.String(), "span_id")
+				return
+			}
+
+			// the extracted span is kept by the context, so that its logs share it, and propagated downstream
+			assert.True(t, ok)
+			assert.Equal(t, testCase.expectedTraceID, sc.TraceID)
+			assert.Equal(t, testSpanID, sc.ParentSpanID)
+			assert.Equal(t, 2, strings.Count(actualResult.String(), `"span_id":"`+sc.SpanID+`"`))
+			assert.Equal(t, 2, strings.Count(actualResult.String(), `"trace_id":"`+testCase.expectedTraceID+`"`))
+
+			md, _ := metadata.FromOutgoingContext(ctx)
+			assert.Equal(t, []string{"00-" + sc.TraceID + "-" + sc.SpanID + "-01"}, md.Get(yawhg.TraceparentHeader))
+		})
+	}
+}
+
+func TestHTTPTraceMiddlewareTraceparent(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "InfoLevel"})
+	defer restore()
+
+	handler := yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sc, ok := yawhg.SpanFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, testTraceID, sc.TraceID)
+		assert.Equal(t, testSpanID, sc.ParentSpanID)
+		assert.Equal(t, "vendor=value", sc.TraceState)
+		assert.Equal(t, "00-"+testTraceID+"-"+sc.SpanID+"-01", r.Header.Get(yawhg.TraceparentHeader))
+
+		yawhg.Infoft(r.Context(), "handled")
+	}), yawhg.HTTPTraceMiddleware)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(yawhg.TraceparentHeader, "00-"+testTraceID+"-"+testSpanID+"-01")
+	req.Header.Set(yawhg.TracestateHeader, "vendor=value")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Contains(t, actualResult.String(), `"trace_id":"`+testTraceID+`"`)
+	assert.Contains(t, actualResult.String(), `"parent_span_id":"`+testSpanID+`"`)
+	assert.Contains(t, actualResult.String(), `"span_id":"`)
+}
+
+func TestB3Propagation(t *testing.T) {
+	_, restore := captureLogs(yawhg.Options{
+		Enabled:        true,
+		AppVersion:     "test",
+		LogLevel:       "InfoLevel",
+		ExtractFormats: []string{yawhg.B3MultiFormat, yawhg.W3CFormat},
+		InjectFormats:  []string{yawhg.B3Format},
+	})
+	defer yawhg.ConfigYawhg(yawhg.Options{Enabled: true, LogLevel: "InfoLevel"})
+	defer restore()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get(yawhg.RequestIDHeader))
+		assert.Empty(t, r.Header.Get(yawhg.TraceparentHeader))
+		assert.Regexp(t, regexp.MustCompile("^0000000000000000a3ce929d0e0e4736-[a-f0-9]{16}-0-"+testSpanID+"$"), r.Header.Get(yawhg.B3Header))
+	}))
+	defer server.Close()
+
+	handler := yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sc, ok := yawhg.SpanFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, "0000000000000000a3ce929d0e0e4736", sc.TraceID)
+		assert.Equal(t, testSpanID, sc.ParentSpanID)
+		assert.False(t, sc.Sampled)
+
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		resp, err := (&http.Client{Transport: yawhg.Transport(nil)}).Do(req.WithContext(r.Context()))
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}), yawhg.HTTPTraceMiddleware)
+
+	// B3 takes precedence over the traceparent header
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(yawhg.B3TraceIDHeader, "a3ce929d0e0e4736")
+	req.Header.Set(yawhg.B3SpanIDHeader, testSpanID)
+	req.Header.Set(yawhg.B3SampledHeader, "0")
+	req.Header.Set(yawhg.TraceparentHeader, "00-"+testTraceID+"-"+testSpanID+"-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+// recordingSpan is an OpenTelemetry span recording its events
+type recordingSpan struct {
+	trace.Span
+	spanContext trace.SpanContext
+	events      map[string][]attribute.KeyValue
+}
+
+func (s *recordingSpan) SpanContext() trace.SpanContext {
+	return s.spanContext
+}
+
+func (s *recordingSpan) IsRecording() bool {
+	return true
+}
+
+func (s *recordingSpan) AddEvent(name string, options ...trace.EventOption) {
+	config := trace.NewEventConfig(options...)
+	s.events[name] = config.Attributes()
+}
+
+func TestOpenTelemetrySpan(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{
+		Enabled:               true,
+		AppVersion:            "test",
+		LogLevel:              "InfoLevel",
+		RecordErrorSpanEvents: true,
+	})
+	defer restore()
+
+	traceID, _ := trace.TraceIDFromHex(testTraceID)
+	spanID, _ := trace.SpanIDFromHex(testSpanID)
+	span := &recordingSpan{
+		spanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled}),
+		events:      map[string][]attribute.KeyValue{},
+	}
+	ctx := trace.ContextWithSpan(context.Background(), span)
+
+	yawhg.Infoft(ctx, "info message")
+	cycle := map[string]interface{}{}
+	cycle["self"] = cycle
+	yawhg.ErrorWithTracing(ctx, yawhg.Fields{"Test": "Foo", "Cycle": cycle, "msg": "error message"})
+
+	assert.Contains(t, actualResult.String(), `"trace_id":"`+testTraceID+`"`)
+	assert.Contains(t, actualResult.String(), `"span_id":"`+testSpanID+`"`)
+
+	// only error-level logs are recorded as span events
+	assert.NotContains(t, span.events, "info message")
+	assert.Contains(t, span.events["error message"], attribute.String("Test", "Foo"))
+	assert.Contains(t, span.events["error message"], attribute.String("trace_id", testTraceID))
+	assert.Contains(t, span.events["error message"], attribute.String("Cycle", `{"self":"!BADVALUE(cycle)"}`))
+}
+
+type requestIDGeneratorTestCase struct {
+	name      string
+	generator yawhg.RequestIDGenerator
+	format    *regexp.Regexp
+	sortable  bool // sorts by creation time at millisecond precision
+}
+
+var requestIDGeneratorTestCases = []requestIDGeneratorTestCase{
+	requestIDGeneratorTestCase{
+		name:      "uuid_v4",
+		generator: yawhg.NewUUIDv4,
+		format:    regexp.MustCompile("^[a-f0-9]{8}-[a-f0-9]{4}-4[a-f0-9]{3}-[89ab][a-f0-9]{3}-[a-f0-9]{12}$"),
+	},
+	requestIDGeneratorTestCase{
+		name:      "uuid_v7",
+		generator: yawhg.NewUUIDv7,
+		format:    regexp.MustCompile("^[a-f0-9]{8}-[a-f0-9]{4}-7[a-f0-9]{3}-[89ab][a-f0-9]{3}-[a-f0-9]{12}$"),
+		sortable:  true,
+	},
+	requestIDGeneratorTestCase{
+		name:      "ulid",
+		generator: yawhg.NewULID,
+		format:    regexp.MustCompile("^[0-7][0-9A-HJKMNP-TV-Z]{25}$"),
+		sortable:  true,
+	},
+	requestIDGeneratorTestCase{
+		name:      "ksuid",
+		generator: yawhg.NewKSUID,
+		format:    regexp.MustCompile("^[0-9A-Za-z]{27}$"),
+	},
+}
+
+func TestRequestIDGenerator(t *testing.T) {
+	defer yawhg.ConfigYawhg(yawhg.Options{Enabled: true, LogLevel: "InfoLevel"})
+
+	for _, testCase := range requestIDGeneratorTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			yawhg.ConfigYawhg(yawhg.Options{Enabled: true, LogLevel: "InfoLevel", RequestIDGenerator: testCase.generator})
+
+			_, first := yawhg.FromContext(context.Background())
+			assert.Regexp(t, testCase.format, first)
+			assert.True(t, yawhg.ValidRequestID(first))
+
+			time.Sleep(2 * time.Millisecond)
+
+			// generated request IDs are unique, and those of time-ordered formats sort by creation time
+			_, second := yawhg.FromContext(context.Background())
+			assert.NotEqual(t, first, second)
+			if testCase.sortable {
+				assert.True(t, first < second, "expected %s to sort before %s", first, second)
+			}
+		})
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	assert.True(t, yawhg.ValidRequestID(testRequestID))
+	assert.True(t, yawhg.ValidRequestID("Root=1-5759e988-bd862e3fe1be46a994272793"))
+	assert.False(t, yawhg.ValidRequestID(""))
+	assert.False(t, yawhg.ValidRequestID("id\r\nX-Injected: true"))
+	assert.False(t, yawhg.ValidRequestID(`id","severity":"error`))
+	assert.False(t, yawhg.ValidRequestID(strings.Repeat("a", 129)))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(yawhg.RequestIDHeader, "id with spaces"))
+	_, reqID := yawhg.FromContext(ctx)
+	assert.NotEqual(t, "id with spaces", reqID)
+}
@@ -0,0 +1,43 @@
+package yawhg
+
+import "context"
+
+type contextFieldsKey struct{}
+
+// NewContext attaches the logger to the context. Its fields are included in every log with tracing from that context,
+// e.g. yawhg.Infoft(ctx, "message") or yawhg.Ctx(ctx).Info("message")
+func NewContext(ctx context.Context, logger Fields) context.Context {
+	return context.WithValue(ctx, contextFieldsKey{}, logger.Copy())
+}
+
+// AddFields attaches additional fields to the logger of the context, such as the user or tenant of a request,
+// so that they are included in every later log with tracing from that context
+func AddFields(ctx context.Context, details Fields) context.Context {
+	data := contextFields(ctx).Copy()
+	for k, v := range details {
+		data[k] = v
+	}
+
+	return context.WithValue(ctx, contextFieldsKey{}, data)
+}
+
+// Ctx returns the logger of the context with tracing information, e.g. yawhg.Ctx(ctx).Info("message")
+// Like WithTracing, the logger does not keep the context, so the level override and buffer of the request do not apply
+func Ctx(ctx context.Context) *Fields {
+	return WithTracing(ctx, Fields{})
+}
+
+// contextFields retrieves the fields attached to the context, which must not be modified
+func contextFields(ctx context.Context) Fields {
+	data, _ := ctx.Value(contextFieldsKey{}).(Fields)
+	return data
+}
+
+// addContextFields adds the fields attached to the context, without overriding the fields of the log
+func (f *Fields) addContextFields(ctx context.Context) {
+	for k, v := range contextFields(ctx) {
+		if _, ok := (*f)[k]; !ok {
+			(*f)[k] = v
+		}
+	}
+}
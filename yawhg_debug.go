@@ -11,14 +11,14 @@ func (f *Fields) Debug(msg string) {
 	(*f)["severity"] = DebugLevel.String()
 	(*f)["msg"] = msg
 
-	structuredWrap(f)
+	structuredWrap(context.Background(), f)
 }
 
 // Debugf logs at the debug severity level (staging and development) with a formatting directive
 func (f *Fields) Debugf(format string, v ...interface{}) {
 	(*f)["severity"] = DebugLevel.String()
 	(*f)["msg"] = fmt.Sprintf(format, v...)
-	structuredWrap(f)
+	structuredWrap(context.Background(), f)
 }
 
 // Debugw is a cumulative logger method for the Fields map that logs at the debug level
@@ -28,7 +28,7 @@ func (f *Fields) Debugw(details Fields) {
 	}
 
 	(*f)["severity"] = DebugLevel.String()
-	structuredWrap(f)
+	structuredWrap(context.Background(), f)
 }
 
 // Debug logs a message at the debug severity level
@@ -54,13 +54,12 @@ func Debugft(ctx context.Context, format string, v ...interface{}) {
 // Debugw creates a debug-level log from a map
 func Debugw(f Fields) {
 	f["severity"] = DebugLevel.String()
-	structuredWrap(&f)
+	structuredWrap(context.Background(), &f)
 }
 
 // DebugWithTracing creates a debug-level log from a map, and extracts tracing information from context
 func DebugWithTracing(ctx context.Context, f Fields, errors ...error) {
 	addErrors(f, errors)
 	f["severity"] = DebugLevel.String()
-	ctx = f.addTracing(ctx)
-	structuredWrap(&f)
+	tracingWrap(ctx, &f)
 }
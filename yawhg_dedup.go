@@ -0,0 +1,184 @@
+package yawhg
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultDedupMaxEntries is the number of distinct logs tracked for duplicates by default
+const defaultDedupMaxEntries int = 1000
+
+// volatileKeys differ between the duplicates of a log, and are left out of its fingerprint without DedupKeys
+var volatileKeys = map[string]bool{
+	"time":          true,
+	"severity":      true,
+	"RequestID":     true,
+	"ResponseTime":  true,
+	requestIDKey:    true,
+	traceIDKey:      true,
+	spanIDKey:       true,
+	parentSpanIDKey: true,
+}
+
+// logDeduper suppresses the duplicates of each log written within a window, and summarizes them once it has elapsed
+type logDeduper struct {
+	window     time.Duration
+	keys       []string
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[uint64]*dedupEntry
+}
+
+// dedupEntry tracks the duplicates of a log, whose summary holds the fields identifying it
+type dedupEntry struct {
+	summary   Fields
+	firstSeen time.Time
+	lastSeen  time.Time
+	repeated  int
+	timer     *time.Timer
+}
+
+// newDeduper returns a deduper for the window, or nil if the window is not positive
+func newDeduper(window time.Duration, keys []string, maxEntries int) *logDeduper {
+	if window <= 0 {
+		return nil
+	}
+
+	if maxEntries <= 0 {
+		maxEntries = defaultDedupMaxEntries
+	}
+
+	return &logDeduper{window: window, keys: keys, maxEntries: maxEntries, entries: map[uint64]*dedupEntry{}}
+}
+
+// check reports whether the fields are written, which is the case unless they duplicate a log of the current window.
+// Logs are written without being tracked once maxEntries logs are tracked
+func (d *logDeduper) check(f *Fields, level Level) bool {
+	fingerprint := d.fingerprint(f, level)
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if entry, ok := d.entries[fingerprint]; ok {
+		entry.repeated++
+		entry.lastSeen = now
+		return false
+	}
+
+	if len(d.entries) >= d.maxEntries {
+		return true
+	}
+
+	entry := &dedupEntry{summary: d.summaryFields(f, level), firstSeen: now, lastSeen: now}
+	entry.timer = time.AfterFunc(d.window, func() {
+		d.expire(fingerprint, entry)
+	})
+	d.entries[fingerprint] = entry
+
+	return true
+}
+
+// fingerprint identifies the duplicates of a log by its level, message and selected keys, or else by its level and
+// every field but the volatile ones, so that logs with a fixed message such as those of the middleware are told apart
+func (d *logDeduper) fingerprint(f *Fields, level Level) uint64 {
+	h := fnv.New64a()
+	if len(d.keys) > 0 {
+		fmt.Fprintf(h, "%d\x00%s", level, samplingMessageKey(f))
+		for _, key := range d.keys {
+			fmt.Fprintf(h, "\x00%s", formatValue((*f)[key]))
+		}
+
+		return h.Sum64()
+	}
+
+	fmt.Fprintf(h, "%d", level)
+	for _, key := range stableKeys(f) {
+		fmt.Fprintf(h, "\x00%s=%s", key, formatValue((*f)[key]))
+	}
+
+	return h.Sum64()
+}
+
+// summaryFields copies the fields identifying a log, which are repeated in the summary of its duplicates
+func (d *logDeduper) summaryFields(f *Fields, level Level) Fields {
+	keys := append([]string{"msg", "Method", loggerKey}, d.keys...)
+	if len(d.keys) == 0 {
+		keys = stableKeys(f)
+	}
+
+	summary := Fields{"severity": level.String()}
+	for _, key := range keys {
+		if value, ok := (*f)[key]; ok {
+			summary[key] = value
+		}
+	}
+
+	return summary
+}
+
+// stableKeys returns the sorted keys of the fields which are not volatile
+func stableKeys(f *Fields) []string {
+	keys := make([]string, 0, len(*f))
+	for key := range *f {
+		if !volatileKeys[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// expire stops tracking a log once its window has elapsed, and writes the summary of its duplicates if any
+func (d *logDeduper) expire(fingerprint uint64, entry *dedupEntry) {
+	d.mu.Lock()
+	// the entry may have been flushed while the timer fired
+	if d.entries[fingerprint] != entry {
+		d.mu.Unlock()
+		return
+	}
+	delete(d.entries, fingerprint)
+	summary := entry.summaryLog()
+	d.mu.Unlock()
+
+	if summary != nil {
+		summary.report(currentConfig())
+	}
+}
+
+// flush writes the summaries of the duplicates of every tracked log, and stops tracking them. It is called once the
+// deduper has been replaced by ConfigYawhg
+func (d *logDeduper) flush() {
+	cfg := currentConfig()
+
+	d.mu.Lock()
+	entries := d.entries
+	d.entries = map[uint64]*dedupEntry{}
+	d.mu.Unlock()
+
+	for _, entry := range entries {
+		entry.timer.Stop()
+		if summary := entry.summaryLog(); summary != nil {
+			summary.report(cfg)
+		}
+	}
+}
+
+// summaryLog returns the summary of the duplicates of the log, or nil if there were none
+func (e *dedupEntry) summaryLog() *Fields {
+	if e.repeated == 0 {
+		return nil
+	}
+
+	summary := e.summary.Copy()
+	summary["repeated"] = e.repeated
+	summary["first_seen"] = e.firstSeen.Format(time.RFC3339Nano)
+	summary["last_seen"] = e.lastSeen.Format(time.RFC3339Nano)
+
+	return &summary
+}
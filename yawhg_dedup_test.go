@@ -0,0 +1,156 @@
+package yawhg_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MarcvanMelle/yawhg"
+)
+
+// volatileFields differ between every log, like the request ID and latency of requests
+func volatileFields(i int) yawhg.Fields {
+	return yawhg.Fields{"RequestID": strconv.Itoa(i), "ResponseTime": float64(i)}
+}
+
+// cyclicFields hold a map containing itself, which must not be formatted recursively
+func cyclicFields(i int) yawhg.Fields {
+	m := map[string]interface{}{}
+	m["self"] = m
+	return yawhg.Fields{"M": m}
+}
+
+// attemptFields repeat every 6 logs
+func attemptFields(i int) yawhg.Fields {
+	return yawhg.Fields{"Attempt": i % 6}
+}
+
+var dedupTestCases = []struct {
+	name              string
+	options           yawhg.Options
+	fields            func(i int) yawhg.Fields
+	wait              time.Duration
+	expectedWritten   int
+	expectedSummaries int
+	expectedSummary   string
+}{
+	{
+		name:              "summarize_duplicates_once_the_window_has_elapsed",
+		options:           yawhg.Options{DedupWindow: 20 * time.Millisecond},
+		fields:            volatileFields,
+		wait:              100 * time.Millisecond,
+		expectedWritten:   1,
+		expectedSummaries: 1,
+		expectedSummary:   `"msg":"dependency down","repeated":11,"severity":"error"`,
+	},
+	{
+		name:              "summarize_duplicates_when_configured_again",
+		options:           yawhg.Options{DedupWindow: time.Hour},
+		fields:            volatileFields,
+		expectedWritten:   1,
+		expectedSummaries: 1,
+		expectedSummary:   `"msg":"dependency down","repeated":11,"severity":"error"`,
+	},
+	{
+		name:              "fingerprint_cyclic_values",
+		options:           yawhg.Options{DedupWindow: time.Hour},
+		fields:            cyclicFields,
+		expectedWritten:   1,
+		expectedSummaries: 1,
+		expectedSummary:   `"msg":"dependency down","repeated":11,"severity":"error"`,
+	},
+	{
+		name:              "fingerprint_every_field_but_the_volatile_ones",
+		options:           yawhg.Options{DedupWindow: time.Hour},
+		fields:            attemptFields,
+		expectedWritten:   6,
+		expectedSummaries: 6,
+		expectedSummary:   `"Attempt":5,"first_seen":`,
+	},
+	{
+		name:              "fingerprint_the_message_and_selected_keys",
+		options:           yawhg.Options{DedupWindow: time.Hour, DedupKeys: []string{"RequestPath"}},
+		fields:            attemptFields,
+		expectedWritten:   1,
+		expectedSummaries: 1,
+		expectedSummary:   `"msg":"dependency down","repeated":11,"severity":"error"`,
+	},
+	{
+		name:              "fingerprint_selected_keys",
+		options:           yawhg.Options{DedupWindow: time.Hour, DedupKeys: []string{"Attempt"}},
+		fields:            attemptFields,
+		expectedWritten:   6,
+		expectedSummaries: 6,
+		expectedSummary:   `"Attempt":5,"first_seen":`,
+	},
+	{
+		name:              "write_untracked_logs_beyond_the_max_entries",
+		options:           yawhg.Options{DedupWindow: time.Hour, DedupKeys: []string{"Attempt"}, DedupMaxEntries: 2},
+		fields:            attemptFields,
+		expectedWritten:   10,
+		expectedSummaries: 2,
+		expectedSummary:   `"Attempt":1,"first_seen":`,
+	},
+}
+
+func TestDedup(t *testing.T) {
+	for _, testCase := range dedupTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			options := testCase.options
+			options.Enabled, options.AppVersion, options.LogLevel = true, "test", "InfoLevel"
+			actualResult, restore := captureLogs(options)
+			defer restore()
+
+			for round := 0; round < 2; round++ {
+				for i := 0; i < 6; i++ {
+					yawhg.WithFields(testCase.fields(round*6 + i)).Error("dependency down")
+				}
+			}
+			time.Sleep(testCase.wait)
+
+			// configuring yawhg writes the remaining summaries, and waits for the logs being written
+			yawhg.ConfigYawhg(yawhg.Options{Enabled: true, LogLevel: "InfoLevel"})
+
+			var written, summaries int
+			for _, line := range strings.Split(strings.TrimSpace(actualResult.String()), "\n") {
+				if strings.Contains(line, `"repeated"`) {
+					summaries++
+					assert.Contains(t, line, `"last_seen":`)
+				} else {
+					written++
+				}
+			}
+
+			assert.Equal(t, testCase.expectedWritten, written)
+			assert.Equal(t, testCase.expectedSummaries, summaries)
+			assert.Contains(t, actualResult.String(), testCase.expectedSummary)
+		})
+	}
+}
+
+func TestDedupRequestLogs(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{
+		Enabled:     true,
+		AppVersion:  "test",
+		LogLevel:    "InfoLevel",
+		DedupWindow: time.Hour,
+	})
+	defer restore()
+
+	handler := yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		yawhg.HTTPLogMiddleware, yawhg.HTTPTraceMiddleware, yawhg.CanonicalHTTPMiddleware)
+	for _, path := range []string{"/a", "/b", "/a"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	// the logs of requests to other paths are not duplicates despite their fixed message, unlike the second request to /a
+	for _, msg := range []string{`"msg":"http request completed"`, `"msg":"canonical log line"`} {
+		assert.Equal(t, 2, strings.Count(actualResult.String(), msg), msg)
+	}
+	assert.Equal(t, 3, strings.Count(actualResult.String(), `"RequestPath":"/b"`))
+}
@@ -0,0 +1,255 @@
+package yawhg
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// maxEncodeDepth bounds the nesting of the values of fields which fail to be encoded as they are
+const maxEncodeDepth int = 10
+
+// badValuePrefix marks the values of fields which could not be encoded
+const badValuePrefix string = "!BADVALUE"
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// marshalSafely encodes a value as JSON, turning the panics of its MarshalJSON methods into errors
+func marshalSafely(v interface{}) (encoded []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return json.Marshal(v)
+}
+
+// formatValue formats a value as it is logged, i.e. as JSON with LogMarshalers resolved and the values which cannot be
+// encoded replaced, or as is for strings. Unlike fmt, it is safe for cyclic values
+func formatValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	resolved, _ := marshalLogValue(value)
+	if encoded, err := marshalSafely(resolved); err == nil {
+		return string(encoded)
+	}
+
+	encoded, _ := json.Marshal(sanitize(reflect.ValueOf(resolved), 0, map[uintptr]bool{}))
+	return string(encoded)
+}
+
+// sanitizeFields returns a copy of the fields in which the values which fail to be encoded are replaced,
+// so that a single field cannot cause the loss of the whole log
+func (f *Fields) sanitizeFields() Fields {
+	sanitized := make(Fields, len(*f))
+	for key, value := range *f {
+		if encoded, err := marshalSafely(value); err == nil {
+			sanitized[key] = json.RawMessage(encoded)
+			continue
+		}
+
+		sanitized[key] = sanitize(reflect.ValueOf(value), 0, map[uintptr]bool{})
+	}
+
+	return sanitized
+}
+
+// sanitize converts a value to one which can be encoded, replacing the values which cannot with a %v-style string
+// marked with !BADVALUE, such as channels, functions, NaN floats, cycles, values nested beyond maxEncodeDepth,
+// and values whose MarshalJSON method fails or panics
+func sanitize(v reflect.Value, depth int, seen map[uintptr]bool) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	if depth > maxEncodeDepth {
+		return badValue("depth limit", nil)
+	}
+
+	// the values of fields promoted from unexported embedded structs cannot be converted to interfaces
+	if v.CanInterface() && (v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType)) {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil
+		}
+
+		encoded, err := marshalSafely(v.Interface())
+		if err != nil {
+			// the value is not formatted, since its String method may be as broken as its MarshalJSON method
+			return badValue(err.Error(), nil)
+		}
+		return json.RawMessage(encoded)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.String:
+		return v.String()
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+			return badValue(v.Type().String(), v)
+		}
+		return v.Float()
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return sanitize(v.Elem(), depth, seen)
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return guardCycle(v, seen, func() interface{} {
+			return sanitize(v.Elem(), depth+1, seen)
+		})
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		return guardCycle(v, seen, func() interface{} {
+			sanitized := make(map[string]interface{}, v.Len())
+			iter := v.MapRange()
+			for iter.Next() {
+				sanitized[formatSafely(iter.Key())] = sanitize(iter.Value(), depth+1, seen)
+			}
+			return sanitized
+		})
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes()
+		}
+		return guardCycle(v, seen, func() interface{} {
+			return sanitizeElements(v, depth, seen)
+		})
+	case reflect.Array:
+		return sanitizeElements(v, depth, seen)
+	case reflect.Struct:
+		sanitized := map[string]interface{}{}
+		sanitizeStruct(v, depth, seen, sanitized)
+		return sanitized
+	}
+
+	// channels, functions, complex numbers and unsafe pointers have no JSON encoding
+	return badValue(v.Type().String(), v)
+}
+
+func sanitizeElements(v reflect.Value, depth int, seen map[uintptr]bool) []interface{} {
+	sanitized := make([]interface{}, v.Len())
+	for i := range sanitized {
+		sanitized[i] = sanitize(v.Index(i), depth+1, seen)
+	}
+
+	return sanitized
+}
+
+// sanitizeStruct adds the exported fields of a struct to the map under their JSON names, flattening embedded structs
+func sanitizeStruct(v reflect.Value, depth int, seen map[uintptr]bool, sanitized map[string]interface{}) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		name, opts := field.Name, ""
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			parts := strings.SplitN(tag, ",", 2)
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			if len(parts) > 1 {
+				opts = parts[1]
+			}
+		}
+
+		value := v.Field(i)
+		if field.Anonymous && value.Kind() == reflect.Struct && name == field.Name {
+			sanitizeStruct(value, depth+1, seen, sanitized)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if strings.Contains(opts, "omitempty") && isEmptyValue(value) {
+			continue
+		}
+
+		sanitized[name] = sanitize(value, depth+1, seen)
+	}
+}
+
+// guardCycle sanitizes a pointer, map or slice unless it is already being sanitized further up, which is a cycle
+func guardCycle(v reflect.Value, seen map[uintptr]bool, fn func() interface{}) interface{} {
+	ptr := v.Pointer()
+	if seen[ptr] {
+		return badValue("cycle", nil)
+	}
+
+	seen[ptr] = true
+	defer delete(seen, ptr)
+
+	return fn()
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+
+	return false
+}
+
+// badValue marks a value which could not be encoded, formatted with %v unless it is nil
+func badValue(reason string, v interface{}) string {
+	if rv, ok := v.(reflect.Value); ok {
+		v = formatSafely(rv)
+	}
+	if v == nil {
+		return fmt.Sprintf("%s(%s)", badValuePrefix, reason)
+	}
+
+	return fmt.Sprintf("%s(%s): %v", badValuePrefix, reason, v)
+}
+
+// formatSafely formats a value with %v, turning the panics of its String method into a description of the panic
+func formatSafely(v reflect.Value) (formatted string) {
+	defer func() {
+		if r := recover(); r != nil {
+			formatted = fmt.Sprintf("panic: %v", r)
+		}
+	}()
+
+	if !v.CanInterface() {
+		return v.Type().String()
+	}
+
+	return fmt.Sprintf("%v", v.Interface())
+}
@@ -0,0 +1,75 @@
+package yawhg_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MarcvanMelle/yawhg"
+)
+
+type panickingMarshaler struct{}
+
+func (panickingMarshaler) MarshalJSON() ([]byte, error) {
+	panic("marshaler bug")
+}
+
+type node struct {
+	Name string
+	Next *node
+}
+
+type embedded struct {
+	ID int `json:"id"`
+}
+
+type account struct {
+	embedded
+	Owner    string      `json:"owner"`
+	Password string      `json:"-"`
+	Balance  float64     `json:"balance"`
+	Notify   chan string `json:"notify,omitempty"`
+}
+
+func TestEncodeBadValues(t *testing.T) {
+	cyclic := &node{Name: "a"}
+	cyclic.Next = &node{Name: "b", Next: cyclic}
+
+	var deep interface{} = make(chan int)
+	for i := 0; i < 20; i++ {
+		deep = map[string]interface{}{"child": deep}
+	}
+
+	selfMap := map[string]interface{}{}
+	selfMap["self"] = selfMap
+
+	actualResult, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "InfoLevel", ErrorHandler: func(error) {}})
+	defer restore()
+
+	yawhg.WithFields(yawhg.Fields{
+		"Valid":     []int{1, 2},
+		"Channel":   make(chan int),
+		"Ratio":     math.NaN(),
+		"Cyclic":    cyclic,
+		"SelfMap":   selfMap,
+		"Deep":      deep,
+		"Marshaler": panickingMarshaler{},
+		"Account":   account{embedded: embedded{ID: 7}, Owner: "ada", Password: "secret", Balance: math.Inf(1)},
+	}).Info("partially encodable")
+
+	var logged map[string]interface{}
+	require.NoError(t, json.Unmarshal(actualResult.Bytes(), &logged))
+
+	assert.Equal(t, "partially encodable", logged["msg"])
+	assert.Equal(t, []interface{}{1.0, 2.0}, logged["Valid"])
+	assert.Regexp(t, `^!BADVALUE\(chan int\): 0x[0-9a-f]+$`, logged["Channel"])
+	assert.Equal(t, "!BADVALUE(float64): NaN", logged["Ratio"])
+	assert.Equal(t, map[string]interface{}{"Name": "a", "Next": map[string]interface{}{"Name": "b", "Next": "!BADVALUE(cycle)"}}, logged["Cyclic"])
+	assert.Equal(t, map[string]interface{}{"self": "!BADVALUE(cycle)"}, logged["SelfMap"])
+	assert.Contains(t, actualResult.String(), `"child":"!BADVALUE(depth limit)"`)
+	assert.Equal(t, "!BADVALUE(panic: marshaler bug)", logged["Marshaler"])
+	assert.Equal(t, map[string]interface{}{"id": 7.0, "owner": "ada", "balance": "!BADVALUE(float64): +Inf", "notify": "!BADVALUE(chan string): <nil>"}, logged["Account"])
+}
@@ -10,14 +10,14 @@ import (
 func (f *Fields) Error(msg string) {
 	(*f)["severity"] = ErrorLevel.String()
 	(*f)["msg"] = msg
-	structuredWrap(f)
+	structuredWrap(context.Background(), f)
 }
 
 // Errorf logs at the error level with a formatting directive
 func (f *Fields) Errorf(format string, v ...interface{}) {
 	(*f)["severity"] = ErrorLevel.String()
 	(*f)["msg"] = fmt.Sprintf(format, v...)
-	structuredWrap(f)
+	structuredWrap(context.Background(), f)
 }
 
 // Errorw is a cumulative logger method for the Fields map that logs at the error level
@@ -27,7 +27,7 @@ func (f *Fields) Errorw(details Fields) {
 	}
 
 	(*f)["severity"] = ErrorLevel.String()
-	structuredWrap(f)
+	structuredWrap(context.Background(), f)
 }
 
 // Error logs a message at the error severity level
@@ -53,13 +53,12 @@ func Errorft(ctx context.Context, format string, v ...interface{}) {
 // Errorw creates an error-level log from a map
 func Errorw(f Fields) {
 	f["severity"] = ErrorLevel.String()
-	structuredWrap(&f)
+	structuredWrap(context.Background(), &f)
 }
 
 // ErrorWithTracing creates an error-level log from a map, and extracts tracing information from context
 func ErrorWithTracing(ctx context.Context, f Fields, errors ...error) {
 	addErrors(f, errors)
 	f["severity"] = ErrorLevel.String()
-	ctx = f.addTracing(ctx)
-	structuredWrap(&f)
+	tracingWrap(ctx, &f)
 }
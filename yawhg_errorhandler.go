@@ -0,0 +1,110 @@
+package yawhg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// ErrorHandler receives the internal errors of yawhg, such as logs which fail to be encoded or written. It may forward
+// the errors by logging through yawhg. The errors raised while it is running, such as those of its own logs when the
+// Destination fails, are reported on stderr instead, so that it is never called recursively
+type ErrorHandler func(err error)
+
+// failure counters, accessed atomically
+var encodeFailures uint64
+var writeFailures uint64
+
+// handlingError is set while the ErrorHandler is running, accessed atomically
+var handlingError int32
+
+// Stats counts the logs which yawhg failed to encode or write since the process started
+type Stats struct {
+	EncodeFailures uint64
+	WriteFailures  uint64
+}
+
+// GetStats returns the counts of the logs which yawhg failed to encode or write
+func GetStats() Stats {
+	return Stats{
+		EncodeFailures: atomic.LoadUint64(&encodeFailures),
+		WriteFailures:  atomic.LoadUint64(&writeFailures),
+	}
+}
+
+// stderrErrorHandler reports an internal error as a structured line on stderr, so that it is not mixed into the logs
+func stderrErrorHandler(err error) {
+	line, _ := json.Marshal(Fields{
+		"time":     time.Now().Format(time.RFC3339Nano),
+		"severity": ErrorLevel.String(),
+		"msg":      "yawhg internal error",
+		"Error":    err.Error(),
+	})
+	os.Stderr.Write(append(line, '\n'))
+}
+
+// handleError reports an internal error of yawhg to the configured ErrorHandler, or on stderr if the ErrorHandler is
+// already running
+func handleError(err error) {
+	if !atomic.CompareAndSwapInt32(&handlingError, 0, 1) {
+		stderrErrorHandler(err)
+		return
+	}
+	defer atomic.StoreInt32(&handlingError, 0)
+
+	currentConfig().errorHandler(err)
+}
+
+// encode encodes the fields as a line of JSON, encoding LogMarshalers with their MarshalLog method. The values of fields
+// which cannot be encoded are replaced with a description marked with !BADVALUE, and fields which still cannot be
+// encoded are replaced with a minimal log holding their time, severity, message and request ID along with the encoding
+// error, so that the log is not lost
+func (f *Fields) encode() []byte {
+	marshaled, marshalErr := f.marshalLogFields()
+	if marshalErr != nil {
+		atomic.AddUint64(&encodeFailures, 1)
+		handleError(fmt.Errorf("encoding log: %v", marshalErr))
+	}
+
+	encoded, err := marshalSafely(marshaled)
+	if err == nil {
+		return append(encoded, '\n')
+	}
+
+	// a log is counted once, even if it also has a value whose MarshalLog failed
+	if marshalErr == nil {
+		atomic.AddUint64(&encodeFailures, 1)
+	}
+	handleError(fmt.Errorf("encoding log: %v", err))
+
+	if encoded, sanitizeErr := marshalSafely(marshaled.sanitizeFields()); sanitizeErr == nil {
+		return append(encoded, '\n')
+	}
+
+	fallback := Fields{"EncodeError": err.Error()}
+	for _, key := range []string{"time", "severity", requestIDKey} {
+		if value, ok := (*f)[key].(string); ok {
+			fallback[key] = value
+		}
+	}
+	if msg, ok := (*f)["msg"]; ok {
+		fallback["msg"] = fmt.Sprint(msg)
+	}
+
+	encoded, _ = json.Marshal(fallback)
+	return append(encoded, '\n')
+}
+
+// writeLog writes an encoded log to the Destination
+func writeLog(encoded []byte) {
+	destinationMu.RLock()
+	_, err := Destination.Write(encoded)
+	destinationMu.RUnlock()
+
+	if err != nil {
+		atomic.AddUint64(&writeFailures, 1)
+		handleError(fmt.Errorf("writing log: %v", err))
+	}
+}
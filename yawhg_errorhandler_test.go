@@ -0,0 +1,115 @@
+package yawhg_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MarcvanMelle/yawhg"
+)
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestErrorHandler(t *testing.T) {
+	var handled []string
+	actualResult, restore := captureLogs(yawhg.Options{
+		Enabled:    true,
+		AppVersion: "test",
+		LogLevel:   "InfoLevel",
+		ErrorHandler: func(err error) {
+			handled = append(handled, err.Error())
+		},
+	})
+	defer restore()
+
+	before := yawhg.GetStats()
+
+	yawhg.WithFields(yawhg.Fields{"Callback": func() {}}).Info("unencodable")
+	assert.Regexp(t, `^\{"Callback":"!BADVALUE\(func\(\)\): 0x[0-9a-f]+","msg":"unencodable","severity":"info","time":"[^"]+","v":"test"\}\n$`, actualResult.String())
+
+	yawhg.Destination = failingWriter{}
+	yawhg.Info("lost")
+
+	assert.Equal(t, []string{"encoding log: json: unsupported type: func()", "writing log: disk full"}, handled)
+	assert.Equal(t, yawhg.Stats{EncodeFailures: before.EncodeFailures + 1, WriteFailures: before.WriteFailures + 1}, yawhg.GetStats())
+}
+
+func TestErrorHandlerLogsDuringReload(t *testing.T) {
+	output := new(syncBuffer)
+	options := yawhg.Options{
+		Enabled:    true,
+		Output:     output,
+		AppVersion: "test",
+		LogLevel:   "InfoLevel",
+		ErrorHandler: func(err error) {
+			// forward the error through yawhg itself
+			yawhg.WithFields(yawhg.Fields{"Forwarded": err.Error()}).Error("yawhg internal error")
+		},
+	}
+	yawhg.ConfigYawhg(options)
+	defer yawhg.ConfigYawhg(yawhg.Options{Enabled: false, LogLevel: "InfoLevel"})
+
+	stop := make(chan struct{})
+	reloaded := make(chan struct{})
+	go func() {
+		defer close(reloaded)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				yawhg.ConfigYawhg(options)
+			}
+		}
+	}()
+
+	// the logs are written one at a time, since the errors raised while the handler is running are written on stderr
+	const numLogs = 100
+	logged := make(chan struct{})
+	go func() {
+		defer close(logged)
+		for i := 0; i < numLogs; i++ {
+			yawhg.WithFields(yawhg.Fields{"Callback": func() {}}).Info("unencodable")
+		}
+	}()
+
+	select {
+	case <-logged:
+	case <-time.After(10 * time.Second):
+		t.Fatal("logging from the error handler deadlocked with ConfigYawhg")
+	}
+	close(stop)
+	<-reloaded
+
+	assert.Equal(t, numLogs, strings.Count(output.String(), `"msg":"yawhg internal error"`))
+}
+
+func TestErrorHandlerLogsToFailingDestination(t *testing.T) {
+	var handled int
+	yawhg.ConfigYawhg(yawhg.Options{
+		Enabled:    true,
+		Output:     failingWriter{},
+		AppVersion: "test",
+		LogLevel:   "InfoLevel",
+		ErrorHandler: func(err error) {
+			handled++
+			// the forwarded log fails as well, which must not call the handler again
+			yawhg.WithFields(yawhg.Fields{"Forwarded": err.Error()}).Error("yawhg internal error")
+		},
+	})
+	defer yawhg.ConfigYawhg(yawhg.Options{Enabled: false, LogLevel: "InfoLevel"})
+
+	before := yawhg.GetStats()
+	yawhg.Info("lost")
+
+	assert.Equal(t, 1, handled)
+	assert.Equal(t, before.WriteFailures+2, yawhg.GetStats().WriteFailures)
+}
@@ -2,7 +2,6 @@ package yawhg
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -19,16 +18,25 @@ func (f Fields) Copy() Fields {
 	return newFields
 }
 
-func (f *Fields) addBaseFields() {
+func (f *Fields) addBaseFields(cfg *runtimeConfig) {
 	(*f)["time"] = time.Now().Format(time.RFC3339Nano)
-	(*f)["v"] = appVersion
+	(*f)["v"] = cfg.appVersion
 }
 
-// addTrading extracts tracing information from context and adds it to the log, if available
+// addTracing extracts tracing information from context and adds it to the log, if available
 // N.B. for yawhg to successfully retrieve the `x-request-id` key, users of yawhg must set the key through metadata (as in the test cases)
-func (f *Fields) addTracing(ctx context.Context) context.Context {
-	copyCtx, requestID := FromContext(ctx)
+func (f *Fields) addTracing(ctx context.Context, cfg *runtimeConfig) context.Context {
+	copyCtx, requestID := fromContext(ctx, cfg)
 	(*f)[requestIDKey] = requestID
+	f.addContextFields(copyCtx)
+
+	if sc, ok := SpanFromContext(copyCtx); ok {
+		(*f)[traceIDKey] = sc.TraceID
+		(*f)[spanIDKey] = sc.SpanID
+		if sc.ParentSpanID != "" {
+			(*f)[parentSpanIDKey] = sc.ParentSpanID
+		}
+	}
 
 	return copyCtx
 }
@@ -39,13 +47,73 @@ func (f *Fields) checkSeverityLevel() (Level, error) {
 		return 0, fmt.Errorf("severity level not set for %s", f)
 	}
 
-	return parseLevel(severityLevel)
+	return ParseLevel(severityLevel)
 }
 
-func (f *Fields) fire() {
-	if err := json.NewEncoder(Destination).Encode(f); err != nil {
-		fmt.Printf("logging through yawhg: %s", err)
+// log sets the severity level and message of the fields and writes them with the context, whose level override and
+// request buffer apply to the log
+func (f *Fields) log(ctx context.Context, cfg *runtimeConfig, level Level, msg string) {
+	(*f)["msg"] = msg
+	f.logAt(ctx, cfg, level)
+}
+
+// logAt sets the severity level of the fields and writes them with the context, like log for fields without a message
+func (f *Fields) logAt(ctx context.Context, cfg *runtimeConfig, level Level) {
+	(*f)["severity"] = level.String()
+	f.addBaseFields(cfg)
+	f.write(ctx, cfg)
+}
+
+// write writes the fields if their severity level rises to the specified threshold, which may be overridden for the
+// request of the context
+func (f *Fields) write(ctx context.Context, cfg *runtimeConfig) {
+	messageLevel, err := f.checkSeverityLevel()
+	if err != nil {
+		handleError(fmt.Errorf("checking log message severity level: %v", err))
+		messageLevel = InfoLevel // default to InfoLevel in case of error
+	}
+
+	// only write the log if the severity level rises to the specified threshold
+	logger, _ := (*f)[loggerKey].(string)
+	if messageLevel < levelThreshold(ctx, cfg, logger) {
+		f.bufferLog(ctx, messageLevel)
+		return
+	}
+
+	if cfg.deduper != nil && !cfg.deduper.check(f, messageLevel) {
+		return
 	}
+
+	if cfg.sampler != nil && !cfg.sampler.sample(f, messageLevel) {
+		return
+	}
+
+	if messageLevel >= ErrorLevel {
+		if cfg.recordErrorSpanEvents {
+			recordSpanEvent(ctx, f)
+		}
+
+		// the logs leading up to an error are written before the error itself
+		if buffer, ok := ctx.Value(requestBufferKey{}).(*requestBuffer); ok {
+			buffer.flush()
+		}
+	}
+
+	f.runHooks(ctx, messageLevel)
+	f.fire()
+}
+
+func (f *Fields) fire() {
+	writeLog(f.encode())
+}
+
+// report writes the logs which yawhg writes on behalf of other logs, such as the reports of sampled logs and the
+// summaries of duplicate logs, which are passed to the hooks but never sampled nor deduplicated themselves
+func (f *Fields) report(cfg *runtimeConfig) {
+	level, _ := f.checkSeverityLevel() // set from the level of the logs reported
+	f.addBaseFields(cfg)
+	f.runHooks(context.Background(), level)
+	f.fire()
 }
 
 func (f *Fields) resetWrapper() {
@@ -0,0 +1,106 @@
+package yawhg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Hook inspects or modifies logs before they are encoded, e.g. to add computed fields, forward errors to an alerting
+// system or increment metrics. Hooks must not log through yawhg
+type Hook interface {
+	// Levels returns the levels of the logs passed to the hook
+	Levels() []Level
+	// Fire is called with every log of the levels which is written or buffered, including the reports of sampled logs
+	// and the summaries of duplicate logs. Its errors and panics are reported to the internal error handler, and the
+	// log is written regardless
+	Fire(*Entry) error
+}
+
+// Entry is a log passed to hooks, which modify it through its Fields
+type Entry struct {
+	Level   Level
+	Fields  Fields
+	Context context.Context
+}
+
+// registeredHook wraps a hook so that the same hook can be added and removed more than once
+type registeredHook struct {
+	Hook
+}
+
+// hooks holds a []*registeredHook, which is replaced rather than modified
+var hooks atomic.Value
+var hooksMu sync.Mutex
+
+// AddHook adds a hook for the logs of its levels, until remove is called
+func AddHook(hook Hook) (remove func()) {
+	registered := &registeredHook{Hook: hook}
+	updateHooks(func(current []*registeredHook) []*registeredHook {
+		return append(current, registered)
+	})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			updateHooks(func(current []*registeredHook) []*registeredHook {
+				for i, h := range current {
+					if h == registered {
+						return append(current[:i], current[i+1:]...)
+					}
+				}
+				return current
+			})
+		})
+	}
+}
+
+// updateHooks replaces the hooks with a modified copy, so that they can be read without locking
+func updateHooks(fn func(current []*registeredHook) []*registeredHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	current, _ := hooks.Load().([]*registeredHook)
+	hooks.Store(fn(append([]*registeredHook(nil), current...)))
+}
+
+// runHooks passes the fields to the hooks of their level
+func (f *Fields) runHooks(ctx context.Context, level Level) {
+	current, _ := hooks.Load().([]*registeredHook)
+	if len(current) == 0 {
+		return
+	}
+
+	entry := &Entry{Level: level, Fields: *f, Context: ctx}
+	for _, hook := range current {
+		if !hasLevel(hook.Levels(), level) {
+			continue
+		}
+
+		if err := fireHook(hook, entry); err != nil {
+			handleError(fmt.Errorf("running yawhg hook: %v", err))
+		}
+	}
+}
+
+// fireHook passes the entry to the hook, recovering from its panics so that the log is still written
+func fireHook(hook Hook, entry *Entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return hook.Fire(entry)
+}
+
+func hasLevel(levels []Level, level Level) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,144 @@
+package yawhg_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MarcvanMelle/yawhg"
+)
+
+// testHook adds a field to the logs of its levels, and records their messages
+type testHook struct {
+	levels   []yawhg.Level
+	messages []string
+	err      error
+}
+
+func (h *testHook) Levels() []yawhg.Level {
+	return h.levels
+}
+
+func (h *testHook) Fire(entry *yawhg.Entry) error {
+	entry.Fields["region"] = "eu-west-1"
+	msg, _ := entry.Fields["msg"].(string)
+	h.messages = append(h.messages, msg)
+	return h.err
+}
+
+func TestHooks(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "DebugLevel"})
+	defer restore()
+
+	hook := &testHook{levels: []yawhg.Level{yawhg.InfoLevel, yawhg.ErrorLevel}}
+	remove := yawhg.AddHook(hook)
+
+	yawhg.WithFields(yawhg.Fields{}).Info("structured")
+	yawhg.Infoft(context.Background(), "text")
+	yawhg.Debug("debug")
+	yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), yawhg.HTTPLogMiddleware).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	remove()
+	yawhg.Info("removed")
+
+	// the request log of the middleware has no message
+	assert.Equal(t, []string{"structured", "text", "", "http request completed"}, hook.messages)
+	for _, line := range strings.Split(strings.TrimSpace(actualResult.String()), "\n") {
+		assert.Equal(t, strings.Contains(line, `"msg":"debug"`) || strings.Contains(line, `"msg":"removed"`), !strings.Contains(line, `"region":"eu-west-1"`), line)
+	}
+}
+
+func TestHookError(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "InfoLevel"})
+	defer restore()
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	defer yawhg.AddHook(&testHook{levels: []yawhg.Level{yawhg.InfoLevel}, err: errors.New("alerting unavailable")})()
+	yawhg.Info("still written")
+
+	w.Close()
+	internal, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+
+	assert.Contains(t, actualResult.String(), `"msg":"still written"`)
+	assert.Contains(t, string(internal), `"Error":"running yawhg hook: alerting unavailable","msg":"yawhg internal error"`)
+	assert.NotContains(t, actualResult.String(), "alerting unavailable")
+}
+
+// panicHook panics on every log of its levels
+type panicHook struct{}
+
+func (panicHook) Levels() []yawhg.Level {
+	return []yawhg.Level{yawhg.InfoLevel}
+}
+
+func (panicHook) Fire(entry *yawhg.Entry) error {
+	panic("alerting client not initialized")
+}
+
+func TestHookPanic(t *testing.T) {
+	var handled []string
+	actualResult, restore := captureLogs(yawhg.Options{
+		Enabled:    true,
+		AppVersion: "test",
+		LogLevel:   "InfoLevel",
+		ErrorHandler: func(err error) {
+			handled = append(handled, err.Error())
+		},
+	})
+	defer restore()
+
+	hook := &testHook{levels: []yawhg.Level{yawhg.InfoLevel}}
+	defer yawhg.AddHook(panicHook{})()
+	defer yawhg.AddHook(hook)()
+	yawhg.Info("still written")
+
+	// the panic neither prevents the log nor the next hooks
+	assert.Contains(t, actualResult.String(), `"msg":"still written"`)
+	assert.Equal(t, []string{"still written"}, hook.messages)
+	assert.Equal(t, []string{"running yawhg hook: panic: alerting client not initialized"}, handled)
+}
+
+func TestHookReports(t *testing.T) {
+	options := yawhg.Options{
+		Enabled:          true,
+		AppVersion:       "test",
+		LogLevel:         "InfoLevel",
+		SamplingRules:    []yawhg.SamplingRule{{Message: "hot path", First: 1}},
+		SamplingInterval: time.Hour,
+		DedupWindow:      time.Hour,
+	}
+	actualResult, restore := captureLogs(options)
+	defer restore()
+
+	hook := &testHook{levels: []yawhg.Level{yawhg.InfoLevel}}
+	defer yawhg.AddHook(hook)()
+
+	for i := 0; i < 3; i++ {
+		yawhg.Info("duplicate")
+		yawhg.WithFields(yawhg.Fields{"attempt": i}).Info("hot path")
+	}
+
+	// configuring again writes the sampling report and the summary of the duplicates
+	yawhg.ConfigYawhg(options)
+
+	assert.ElementsMatch(t, []string{"duplicate", "hot path", "duplicate", "log entries suppressed by sampling"}, hook.messages)
+	for _, line := range strings.Split(strings.TrimSpace(actualResult.String()), "\n") {
+		assert.Contains(t, line, `"region":"eu-west-1"`)
+	}
+}
@@ -10,14 +10,14 @@ import (
 func (f *Fields) Info(msg string) {
 	(*f)["severity"] = InfoLevel.String()
 	(*f)["msg"] = msg
-	structuredWrap(f)
+	structuredWrap(context.Background(), f)
 }
 
 // Infof logs at the info level with a formatting directive
 func (f *Fields) Infof(format string, v ...interface{}) {
 	(*f)["severity"] = InfoLevel.String()
 	(*f)["msg"] = fmt.Sprintf(format, v...)
-	structuredWrap(f)
+	structuredWrap(context.Background(), f)
 }
 
 // Infow is a cumulative logger method for the Fields map that logs at the info level
@@ -27,7 +27,7 @@ func (f *Fields) Infow(details Fields) {
 	}
 
 	(*f)["severity"] = InfoLevel.String()
-	structuredWrap(f)
+	structuredWrap(context.Background(), f)
 }
 
 // Info logs a message at the info severity level
@@ -53,13 +53,12 @@ func Infoft(ctx context.Context, format string, v ...interface{}) {
 // Infow creates an info-level log from a map
 func Infow(f Fields) {
 	f["severity"] = InfoLevel.String()
-	structuredWrap(&f)
+	structuredWrap(context.Background(), &f)
 }
 
 // InfoWithTracing creates an info-level log from a map, and extracts tracing information from context
 func InfoWithTracing(ctx context.Context, f Fields, errors ...error) {
 	addErrors(f, errors)
 	f["severity"] = InfoLevel.String()
-	ctx = f.addTracing(ctx)
-	structuredWrap(&f)
+	tracingWrap(ctx, &f)
 }
@@ -1,8 +1,14 @@
 package yawhg
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
 )
 
 // Level type
@@ -29,8 +35,97 @@ func (level Level) String() string {
 	return "unknown"
 }
 
-// parseLevel takes a string level and returns the level enum
-func parseLevel(lvl string) (Level, error) {
+// levelMu guards the level set by SetLevel, which a temporary level reverts to once it expires
+var levelMu sync.Mutex
+var baseLogLevel Level
+var levelExpiry time.Time
+var revertLevel *time.Timer
+
+// GetLevel returns the level from which logs are written
+func GetLevel() Level {
+	return currentConfig().level
+}
+
+// setGlobalLevel replaces the level of the configuration
+func setGlobalLevel(level Level) {
+	updateConfig(func(cfg *runtimeConfig) {
+		cfg.level = level
+	})
+}
+
+// SetLevel sets the level from which logs are written, replacing any temporary level
+func SetLevel(level Level) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	stopTemporaryLevel()
+	baseLogLevel = level
+	setGlobalLevel(level)
+	levelChanged(LevelChange{Level: level.String()})
+}
+
+// SetTemporaryLevel sets the level from which logs are written for the duration of the ttl,
+// after which it reverts to the level set by SetLevel or Options.LogLevel
+func SetTemporaryLevel(level Level, ttl time.Duration) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	stopTemporaryLevel()
+	setGlobalLevel(level)
+	levelExpiry = time.Now().Add(ttl)
+	levelChanged(LevelChange{Level: level.String(), Expires: levelExpiry})
+
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		levelMu.Lock()
+		defer levelMu.Unlock()
+
+		// the temporary level may have been replaced while the timer fired
+		if revertLevel == timer {
+			revertLevel = nil
+			levelExpiry = time.Time{}
+			setGlobalLevel(baseLogLevel)
+			levelChanged(LevelChange{Level: baseLogLevel.String()})
+		}
+	})
+	revertLevel = timer
+}
+
+// levelExpiresAt returns when the current temporary level expires, or the zero time if the level is not temporary
+func levelExpiresAt() time.Time {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	return levelExpiry
+}
+
+// stopTemporaryLevel cancels the revert of the current temporary level, if any. levelMu must be held
+func stopTemporaryLevel() {
+	if revertLevel != nil {
+		revertLevel.Stop()
+		revertLevel = nil
+	}
+	levelExpiry = time.Time{}
+}
+
+// levelThreshold returns the level from which the logs of a logger are written with the context, which is the level
+// of a signed override of the request, else the level of the component of a named logger, else the global level
+func levelThreshold(ctx context.Context, cfg *runtimeConfig, logger string) Level {
+	if override, ok := ctx.Value(levelOverrideKey{}).(levelOverride); ok {
+		return override.level
+	}
+
+	if logger != "" {
+		if level, ok := cfg.componentLevel(logger); ok {
+			return level
+		}
+	}
+
+	return cfg.level
+}
+
+// ParseLevel takes a string level and returns the level enum
+func ParseLevel(lvl string) (Level, error) {
 	switch strings.ToLower(lvl) {
 	case "info":
 		return InfoLevel, nil
@@ -43,3 +138,47 @@ func parseLevel(lvl string) (Level, error) {
 	var l Level
 	return l, fmt.Errorf("not a valid Level: %q", lvl)
 }
+
+// optionLevel converts a level option such as "DebugLevel" to the level enum, falling back to the default if unset or
+// unknown
+func optionLevel(lvl string, fallback Level) Level {
+	level, err := parseOptionLevel(lvl)
+	if err != nil {
+		return fallback
+	}
+
+	return level
+}
+
+// parseOptionLevel converts a level option such as "DebugLevel" or "debug" to the level enum
+func parseOptionLevel(lvl string) (Level, error) {
+	switch lvl {
+	case "DebugLevel":
+		return DebugLevel, nil
+	case "InfoLevel":
+		return InfoLevel, nil
+	case "ErrorLevel":
+		return ErrorLevel, nil
+	}
+
+	return ParseLevel(lvl)
+}
+
+// codeLevel chooses the level of a gRPC call log from its status code, where server faults are errors
+func codeLevel(code codes.Code) Level {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return ErrorLevel
+	}
+
+	return InfoLevel
+}
+
+// statusLevel chooses the level of an access log from the class of the response status code
+func statusLevel(status int) Level {
+	if status >= http.StatusInternalServerError {
+		return ErrorLevel
+	}
+
+	return InfoLevel
+}
@@ -0,0 +1,144 @@
+package yawhg
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// LogMarshaler is implemented by types which control how they are logged, e.g. to omit secrets or rename fields.
+// MarshalLog takes precedence over MarshalJSON for the values of Fields, and for the values added to an ObjectEncoder
+type LogMarshaler interface {
+	MarshalLog(enc ObjectEncoder) error
+}
+
+// ObjectEncoder builds the object logged for a LogMarshaler
+type ObjectEncoder interface {
+	// Add adds a field, whose value is encoded like the values of Fields
+	Add(key string, value interface{})
+	// AddObject adds a field holding the object logged for a LogMarshaler, returning the error of its MarshalLog
+	AddObject(key string, value LogMarshaler) error
+	// AddArray adds a field holding an array, whose elements are encoded like the values of Fields
+	AddArray(key string, values ...interface{}) error
+}
+
+// ObjectFunc adapts a function to a LogMarshaler
+type ObjectFunc func(enc ObjectEncoder) error
+
+// MarshalLog calls the function
+func (fn ObjectFunc) MarshalLog(enc ObjectEncoder) error {
+	return fn(enc)
+}
+
+// Object returns a value logged as the object built by the function
+func Object(fn func(enc ObjectEncoder) error) ObjectFunc {
+	return ObjectFunc(fn)
+}
+
+// LogArray holds values logged as an array, whose elements are encoded like the values of Fields
+type LogArray []interface{}
+
+// Array returns a value logged as an array of the values, which may themselves be LogMarshalers
+func Array(values ...interface{}) LogArray {
+	return LogArray(values)
+}
+
+// objectEncoder builds the object logged for a LogMarshaler as a map
+type objectEncoder map[string]interface{}
+
+func (enc objectEncoder) Add(key string, value interface{}) {
+	enc[key], _ = marshalLogValue(value)
+}
+
+func (enc objectEncoder) AddObject(key string, value LogMarshaler) error {
+	var err error
+	enc[key], err = marshalLogValue(value)
+	return err
+}
+
+func (enc objectEncoder) AddArray(key string, values ...interface{}) error {
+	var err error
+	enc[key], err = marshalLogValue(LogArray(values))
+	return err
+}
+
+// marshalLogFields returns the fields with the values of LogMarshalers and LogArrays replaced with the objects and
+// arrays they are logged as, or the fields themselves if there are none. A value whose MarshalLog fails or panics is
+// replaced with a description of the error marked with !BADVALUE, and the first error by key is returned
+func (f *Fields) marshalLogFields() (*Fields, error) {
+	var marshaled Fields
+	var failedKeys []string
+	var errs map[string]error
+
+	for key, value := range *f {
+		switch value.(type) {
+		case LogMarshaler, LogArray:
+		default:
+			continue
+		}
+
+		// the fields are copied, since cumulative loggers keep the original values for their later logs
+		if marshaled == nil {
+			marshaled = f.Copy()
+		}
+
+		resolved, err := marshalLogValue(value)
+		marshaled[key] = resolved
+		if err != nil {
+			if errs == nil {
+				errs = map[string]error{}
+			}
+			failedKeys = append(failedKeys, key)
+			errs[key] = err
+		}
+	}
+
+	if marshaled == nil {
+		return f, nil
+	}
+	if len(failedKeys) > 0 {
+		sort.Strings(failedKeys)
+		return &marshaled, fmt.Errorf("field %s: %v", failedKeys[0], errs[failedKeys[0]])
+	}
+
+	return &marshaled, nil
+}
+
+// marshalLogValue returns the object or array a LogMarshaler or LogArray is logged as, or any other value as it is
+func marshalLogValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case LogMarshaler:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil, nil
+		}
+
+		enc := objectEncoder{}
+		if err := marshalLogSafely(v, enc); err != nil {
+			return badValue(err.Error(), nil), err
+		}
+		return map[string]interface{}(enc), nil
+	case LogArray:
+		var firstErr error
+		elements := make([]interface{}, len(v))
+		for i, element := range v {
+			var err error
+			if elements[i], err = marshalLogValue(element); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return elements, firstErr
+	}
+
+	return value, nil
+}
+
+// marshalLogSafely calls MarshalLog, turning its panics into errors
+func marshalLogSafely(v LogMarshaler, enc ObjectEncoder) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return v.MarshalLog(enc)
+}
@@ -0,0 +1,155 @@
+package yawhg_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MarcvanMelle/yawhg"
+)
+
+type user struct {
+	Name     string
+	Password string
+	Roles    []string
+	Manager  *user
+}
+
+// MarshalJSON exposes the password, which MarshalLog should take precedence over
+func (u *user) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"Name": u.Name, "Password": u.Password})
+}
+
+func (u *user) MarshalLog(enc yawhg.ObjectEncoder) error {
+	enc.Add("name", u.Name)
+
+	roles := make([]interface{}, len(u.Roles))
+	for i, role := range u.Roles {
+		roles[i] = role
+	}
+	if err := enc.AddArray("roles", roles...); err != nil {
+		return err
+	}
+
+	return enc.AddObject("manager", u.Manager)
+}
+
+type failingMarshaler struct {
+	err error
+}
+
+func (m failingMarshaler) MarshalLog(enc yawhg.ObjectEncoder) error {
+	if m.err == nil {
+		panic("marshal log bug")
+	}
+	return m.err
+}
+
+func TestLogMarshaler(t *testing.T) {
+	alice := &user{Name: "alice", Password: "hunter2", Roles: []string{"admin"}}
+	bob := &user{Name: "bob", Password: "letmein", Manager: alice}
+
+	testCases := []struct {
+		name          string
+		fields        yawhg.Fields
+		expected      map[string]interface{}
+		expectedError string
+	}{
+		{
+			name:   "MarshalLog takes precedence over MarshalJSON",
+			fields: yawhg.Fields{"User": bob},
+			expected: map[string]interface{}{
+				"User": map[string]interface{}{
+					"name":  "bob",
+					"roles": []interface{}{},
+					"manager": map[string]interface{}{
+						"name":    "alice",
+						"roles":   []interface{}{"admin"},
+						"manager": nil,
+					},
+				},
+			},
+		},
+		{
+			name: "Object and Array",
+			fields: yawhg.Fields{
+				"Request": yawhg.Object(func(enc yawhg.ObjectEncoder) error {
+					enc.Add("path", "/users")
+					enc.Add("user", alice)
+					return nil
+				}),
+				"Users": yawhg.Array(alice, "anonymous"),
+			},
+			expected: map[string]interface{}{
+				"Request": map[string]interface{}{
+					"path": "/users",
+					"user": map[string]interface{}{"name": "alice", "roles": []interface{}{"admin"}, "manager": nil},
+				},
+				"Users": []interface{}{
+					map[string]interface{}{"name": "alice", "roles": []interface{}{"admin"}, "manager": nil},
+					"anonymous",
+				},
+			},
+		},
+		{
+			name:          "MarshalLog error",
+			fields:        yawhg.Fields{"Valid": 1, "Broken": failingMarshaler{err: errors.New("no session")}},
+			expected:      map[string]interface{}{"Valid": 1.0, "Broken": "!BADVALUE(no session)"},
+			expectedError: "encoding log: field Broken: no session",
+		},
+		{
+			name:          "MarshalLog panic",
+			fields:        yawhg.Fields{"Users": yawhg.Array(failingMarshaler{})},
+			expected:      map[string]interface{}{"Users": []interface{}{"!BADVALUE(panic: marshal log bug)"}},
+			expectedError: "encoding log: field Users: panic: marshal log bug",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var handled []string
+			actualResult, restore := captureLogs(yawhg.Options{
+				Enabled:    true,
+				AppVersion: "test",
+				LogLevel:   "InfoLevel",
+				ErrorHandler: func(err error) {
+					handled = append(handled, err.Error())
+				},
+			})
+			defer restore()
+
+			yawhg.WithFields(tc.fields).Info("marshaled")
+
+			var logged map[string]interface{}
+			require.NoError(t, json.Unmarshal(actualResult.Bytes(), &logged))
+			for _, key := range []string{"msg", "severity", "time", "v"} {
+				delete(logged, key)
+			}
+
+			assert.Equal(t, tc.expected, logged)
+			assert.NotContains(t, actualResult.String(), "hunter2")
+			if tc.expectedError == "" {
+				assert.Empty(t, handled)
+			} else {
+				assert.Equal(t, []string{tc.expectedError}, handled)
+			}
+		})
+	}
+}
+
+func TestLogMarshalerCumulativeLogger(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "InfoLevel"})
+	defer restore()
+
+	alice := &user{Name: "alice"}
+	logger := yawhg.WithFields(yawhg.Fields{"User": alice})
+	logger.Info("first")
+	logger.Info("second")
+
+	assert.Equal(t, alice, (*logger)["User"])
+	assert.Equal(t, 2, strings.Count(actualResult.String(), `"User":{"manager":null,"name":"alice","roles":[]}`))
+}
@@ -1,13 +1,16 @@
 package yawhg
 
 import (
-	"bytes"
 	"context"
+	"fmt"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
 
-	"github.com/gofrs/uuid"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 // AddMiddleware adds middleware to a Handler
@@ -21,17 +24,16 @@ func AddMiddleware(h http.Handler, middleware ...func(http.Handler) http.Handler
 // GRPCLogInterceptor logs server side incoming requests and responses
 func GRPCLogInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 	t := time.Now()
+	cfg := currentConfig()
 
-	_, requestID := FromContext(ctx)
+	// the request and response are logged with the same request ID, even if it was generated for the call
+	logCtx, requestID := fromContext(ctx, cfg)
 
-	InfoWithTracing(
-		ctx,
-		Fields{
-			"Method":    info.FullMethod,
-			"Request":   req,
-			"RequestID": requestID,
-		},
-	)
+	withTracing(logCtx, cfg, Fields{
+		"Method":    info.FullMethod,
+		"Request":   req,
+		"RequestID": requestID,
+	}).logAt(logCtx, cfg, InfoLevel)
 
 	resp, err := handler(ctx, req)
 
@@ -46,58 +48,239 @@ func GRPCLogInterceptor(ctx context.Context, req interface{}, info *grpc.UnarySe
 		payload["Error"] = err.Error()
 	}
 
-	InfoWithTracing(ctx, payload)
+	withTracing(logCtx, cfg, payload).logAt(logCtx, cfg, InfoLevel)
 
 	return resp, err
 }
 
-// GRPCTraceInterceptor adds x-request-id to incoming context if not present
+// GRPCTraceInterceptor adds x-request-id to incoming context if not present, and to the response header metadata.
+// It attaches the span of the call to the context, a child of the span in the incoming metadata in one of the
+// Options.ExtractFormats or else the root span of a new trace. With Options.BufferRequestLogs, it buffers the logs of
+// the call below the LogLevel until the call fails. A signed x-yawhg-level metadata sets the log level of the call
 func GRPCTraceInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	requestIDCtx, _ := FromContext(ctx)
+	cfg := currentConfig()
+
+	requestIDCtx, requestID := fromContext(ctx, cfg)
+	requestIDCtx = addSpanFromMetadata(requestIDCtx, cfg)
+	requestIDCtx = addLevelOverrideFromMetadata(requestIDCtx, cfg)
+	grpc.SetHeader(ctx, metadata.Pairs(cfg.requestIDHeader, requestID)) // fails only outside of a gRPC server
+
+	requestIDCtx, buffer := startRequestBuffer(requestIDCtx, cfg)
 	resp, err := handler(requestIDCtx, req)
+	buffer.end(err != nil)
+
 	return resp, err
 }
 
-// HTTPLogMiddleware logs the incoming request to the http server
+// GRPCStreamLogInterceptor logs the opening and closing of server side streams, along with the number of messages
+// sent and received. Every message is logged at the debug level as well when Options.LogStreamMessages is set
+func GRPCStreamLogInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	t := time.Now()
+	cfg := currentConfig()
+
+	ctx, requestID := fromContext(ss.Context(), cfg)
+	stream := newServerStream(ss, ctx, cfg, info.FullMethod, requestID)
+
+	withTracing(ctx, cfg, Fields{
+		"Method":       info.FullMethod,
+		"RequestID":    requestID,
+		"ClientStream": info.IsClientStream,
+		"ServerStream": info.IsServerStream,
+	}).log(ctx, cfg, InfoLevel, "grpc stream opened")
+
+	err := handler(srv, stream)
+
+	withTracing(ctx, cfg, Fields{
+		"Method":           info.FullMethod,
+		"RequestID":        requestID,
+		"ResponseTime":     time.Since(t).Seconds(),
+		"MessagesSent":     atomic.LoadInt64(&stream.sent),
+		"MessagesReceived": atomic.LoadInt64(&stream.received),
+		"StatusCode":       status.Code(err).String(),
+	}, err).log(ctx, cfg, InfoLevel, "grpc stream closed")
+
+	return err
+}
+
+// GRPCStreamTraceInterceptor adds x-request-id to the context of incoming streams if not present,
+// and to the response header metadata. It attaches the span of the stream to its context like GRPCTraceInterceptor.
+// With Options.BufferRequestLogs, it buffers the logs of the stream below the
+// LogLevel until the stream fails. A signed x-yawhg-level metadata sets the log level of the stream
+func GRPCStreamTraceInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	cfg := currentConfig()
+
+	requestIDCtx, requestID := fromContext(ss.Context(), cfg)
+	requestIDCtx = addSpanFromMetadata(requestIDCtx, cfg)
+	requestIDCtx = addLevelOverrideFromMetadata(requestIDCtx, cfg)
+	ss.SetHeader(metadata.Pairs(cfg.requestIDHeader, requestID))
+
+	requestIDCtx, buffer := startRequestBuffer(requestIDCtx, cfg)
+	err := handler(srv, newServerStream(ss, requestIDCtx, cfg, info.FullMethod, requestID))
+	buffer.end(err != nil)
+
+	return err
+}
+
+// serverStream wraps a grpc.ServerStream so that handlers receive a context carrying the request ID,
+// and counts the messages passing through the stream
+type serverStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	cfg       *runtimeConfig
+	method    string
+	requestID string
+	sent      int64
+	received  int64
+}
+
+// newServerStream wraps the stream, whose messages are logged with the configuration of the start of the stream
+func newServerStream(ss grpc.ServerStream, ctx context.Context, cfg *runtimeConfig, method string, requestID string) *serverStream {
+	return &serverStream{ServerStream: ss, ctx: ctx, cfg: cfg, method: method, requestID: requestID}
+}
+
+// Context returns the request ID context in place of the context of the wrapped stream
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// SendMsg counts and optionally logs every message successfully sent on the stream
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		atomic.AddInt64(&s.sent, 1)
+		s.logMessage("sent", m)
+	}
+
+	return err
+}
+
+// RecvMsg counts and optionally logs every message successfully received on the stream
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		atomic.AddInt64(&s.received, 1)
+		s.logMessage("received", m)
+	}
+
+	return err
+}
+
+func (s *serverStream) logMessage(direction string, m interface{}) {
+	if !s.cfg.logStreamMessages {
+		return
+	}
+
+	withTracing(s.ctx, s.cfg, Fields{
+		"Method":    s.method,
+		"RequestID": s.requestID,
+		"Direction": direction,
+		"Message":   m,
+	}).logAt(s.ctx, s.cfg, DebugLevel)
+}
+
+// HTTPLogMiddleware logs the incoming request to the http server, and the status code, size and latency of the response
+// once it has completed. Panics in the handler are logged before being propagated to the http server. Connections
+// hijacked by the handler, e.g. for WebSockets, are logged with Hijacked, and the 101 status unless one was written
 func HTTPLogMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		buf := bytes.Buffer{}
-		buf.ReadFrom(r.Body)
+		t := time.Now()
+		cfg := currentConfig()
 
-		InfoWithTracing(r.Context(), Fields{
+		payload := Fields{
 			"Method":       r.Method,
 			"RequestPath":  r.URL.Path,
 			"RequestQuery": r.URL.RawQuery,
-			"RequestBody":  buf.String(),
-		})
+		}
+		r = logRequestBody(r, cfg, payload)
+
+		withTracing(r.Context(), cfg, payload).logAt(r.Context(), cfg, InfoLevel)
+
+		rw, recorder := wrapResponseWriter(w)
+
+		defer func() {
+			payload := Fields{
+				"Method":        r.Method,
+				"RequestPath":   r.URL.Path,
+				"Status":        recorder.Status(),
+				"ResponseBytes": recorder.bytes,
+				"ResponseTime":  time.Since(t).Seconds(),
+			}
+			if recorder.hijacked {
+				// the response written to the hijacked connection is unknown
+				payload["Hijacked"] = true
+			}
+
+			if p := recover(); p != nil {
+				payload["Status"] = http.StatusInternalServerError
+				payload["Panic"] = fmt.Sprint(p)
+				withTracing(r.Context(), cfg, payload).log(r.Context(), cfg, ErrorLevel, "http request completed")
+
+				panic(p)
+			}
 
-		next.ServeHTTP(w, r)
+			withTracing(r.Context(), cfg, payload).log(r.Context(), cfg, statusLevel(recorder.Status()), "http request completed")
+		}()
+
+		next.ServeHTTP(rw, r)
 	})
 }
 
-// HTTPTraceMiddleware adds a x-request-id to the http header, and request context if not present
+// HTTPTraceMiddleware adds a x-request-id to the http header, and request context if not present, and echoes it in the
+// response header.
+// A new span of the trace found in the header in one of the Options.ExtractFormats is attached to the request context
+// and header as well. With Options.BufferRequestLogs, it buffers the logs of the request below the LogLevel until
+// the request fails with a 5xx status code. A signed x-yawhg-level header sets the log level of the request
 func HTTPTraceMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var err error
-		var newUUID uuid.UUID
+		cfg := currentConfig()
 
-		reqID := r.Header.Get(RequestIDHeader)
-		if l := len(reqID); l == 0 {
-			newUUID, err = uuid.NewV4()
-			if err != nil {
-				WithTracing(r.Context(), Fields{}, err).Error("failed to generate new request ID")
+		var reqID string
+		if hasFormat(cfg.extractFormats, RequestIDFormat) {
+			reqID = r.Header.Get(cfg.requestIDHeader)
+		}
+
+		if !cfg.validateRequestID(reqID) {
+			if len(reqID) > maxRequestIDLength {
+				reqID = reqID[:maxRequestIDLength]
+			}
+			if reqID != "" {
+				withTracing(r.Context(), cfg, Fields{"InvalidRequestID": strconv.Quote(reqID)}).log(r.Context(), cfg, DebugLevel, "replacing invalid request ID")
 			}
 
-			reqID = newUUID.String() // generate new request ID if not present
-		} else if l > 36 {
-			reqID = reqID[:36] // truncate if longer than UUID
+			reqID, err = cfg.generateRequestID() // generate new request ID if not present or invalid
+			if err != nil {
+				withTracing(r.Context(), cfg, Fields{}, err).log(r.Context(), cfg, ErrorLevel, "failed to generate new request ID")
+			}
 		}
 
 		if err != nil {
 			next.ServeHTTP(w, r)
 		} else {
-			ctx, updatedReq := AddToHeader(r, reqID)
-			next.ServeHTTP(w, updatedReq.WithContext(ctx))
+			w.Header().Set(cfg.requestIDHeader, reqID) // echo the request ID, so that it can be quoted by clients
+
+			ctx, updatedReq := addToHeader(r, cfg, reqID)
+			ctx, updatedReq = addSpanToHeader(ctx, cfg, updatedReq)
+			ctx = addLevelOverrideToHeader(ctx, cfg, updatedReq)
+
+			ctx, buffer := startRequestBuffer(ctx, cfg)
+			if buffer == nil {
+				next.ServeHTTP(w, updatedReq.WithContext(ctx))
+				return
+			}
+
+			rw, recorder := wrapResponseWriter(w)
+
+			defer func() {
+				if p := recover(); p != nil {
+					buffer.end(true)
+					panic(p)
+				}
+
+				buffer.end(recorder.Status() >= http.StatusInternalServerError)
+			}()
+
+			next.ServeHTTP(rw, updatedReq.WithContext(ctx))
 		}
 	})
 }
@@ -1,13 +1,23 @@
 package yawhg_test
 
 import (
+	"bufio"
 	"context"
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/MarcvanMelle/yawhg"
 )
@@ -60,6 +70,647 @@ func TestHTTPTraceMiddleware(t *testing.T) {
 			}
 
 			handler.ServeHTTP(w, req)
+
+			// the request ID is echoed in the response
+			if testCase.requestID != "" {
+				assert.Equal(t, testCase.requestID, w.Header().Get(yawhg.RequestIDHeader))
+			} else {
+				assert.NotEmpty(t, w.Header().Get(yawhg.RequestIDHeader))
+			}
+		})
+	}
+}
+
+func TestHTTPTraceMiddlewareRequestIDHeader(t *testing.T) {
+	yawhg.ConfigYawhg(yawhg.Options{Enabled: true, LogLevel: "InfoLevel", RequestIDHeader: "X-Correlation-ID"})
+	defer yawhg.ConfigYawhg(yawhg.Options{Enabled: true, LogLevel: "InfoLevel"})
+
+	handler := yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, testRequestID, yawhg.FromHeader(r))
+
+		_, reqID := yawhg.FromContext(r.Context())
+		assert.Equal(t, testRequestID, reqID)
+	}), yawhg.HTTPTraceMiddleware)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Correlation-ID", testRequestID)
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, testRequestID, w.Header().Get("X-Correlation-ID"))
+	assert.Empty(t, w.Header().Get(yawhg.RequestIDHeader))
+}
+
+func TestHTTPTraceMiddlewareInvalidRequestID(t *testing.T) {
+	handler := yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Regexp(t, regexp.MustCompile("^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}$"), r.Header.Get(yawhg.RequestIDHeader))
+	}), yawhg.HTTPTraceMiddleware)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(yawhg.RequestIDHeader, `forged","severity":"error`)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+// fakeServerStream is a minimal grpc.ServerStream which records sent messages and replays received ones
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	header   metadata.MD
+	sent     []interface{}
+	received []string
+}
+
+func (s *fakeServerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeServerStream) SendMsg(m interface{}) error {
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+func (s *fakeServerStream) RecvMsg(m interface{}) error {
+	if len(s.received) == 0 {
+		return io.EOF
+	}
+
+	*(m.(*string)) = s.received[0]
+	s.received = s.received[1:]
+	return nil
+}
+
+func TestGRPCStreamInterceptors(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{
+		Enabled:           true,
+		AppVersion:        "test",
+		LogLevel:          "DebugLevel",
+		LogStreamMessages: true,
+	})
+	defer restore()
+
+	stream := &fakeServerStream{
+		ctx:      metadata.NewIncomingContext(context.Background(), metadata.Pairs(yawhg.RequestIDHeader, testRequestID)),
+		received: []string{"ping", "ping"},
+	}
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream", IsClientStream: true, IsServerStream: true}
+
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		_, requestID := yawhg.FromContext(ss.Context())
+		assert.Equal(t, testRequestID, requestID)
+
+		for {
+			var msg string
+			if err := ss.RecvMsg(&msg); err != nil {
+				break
+			}
+
+			if err := ss.SendMsg("pong"); err != nil {
+				return err
+			}
+		}
+
+		return status.Error(codes.Unavailable, "stream ended")
+	}
+
+	err := yawhg.GRPCStreamTraceInterceptor(nil, stream, info, func(srv interface{}, ss grpc.ServerStream) error {
+		return yawhg.GRPCStreamLogInterceptor(srv, ss, info, handler)
+	})
+
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Len(t, stream.sent, 2)
+	assert.Equal(t, []string{testRequestID}, stream.header.Get(yawhg.RequestIDHeader))
+
+	for _, result := range []string{
+		`"msg":"grpc stream opened"`,
+		`"msg":"grpc stream closed"`,
+		`"MessagesSent":2`,
+		`"MessagesReceived":2`,
+		`"StatusCode":"Unavailable"`,
+		`"Direction":"received"`,
+		`"request_id":"` + testRequestID + `"`,
+	} {
+		assert.Contains(t, actualResult.String(), result)
+	}
+}
+
+type logMiddlewareTestCase struct {
+	name           string
+	handler        func(t *testing.T, w http.ResponseWriter, r *http.Request)
+	expectedResult []string
+}
+
+var logMiddlewareTestCases = []logMiddlewareTestCase{
+	logMiddlewareTestCase{
+		name: "log_status_and_size_of_the_response",
+		handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("created"))
+		},
+		expectedResult: []string{`"Status":201`, `"ResponseBytes":7`, `"severity":"info"`},
+	},
+	logMiddlewareTestCase{
+		name: "log_server_errors_at_the_error_level",
+		handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		},
+		expectedResult: []string{`"Status":503`, `"severity":"error"`},
+	},
+	logMiddlewareTestCase{
+		name: "keep_optional_interfaces_of_the_response_writer",
+		handler: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+			_, isFlusher := w.(http.Flusher)
+			assert.True(t, isFlusher)
+
+			_, isHijacker := w.(http.Hijacker)
+			assert.False(t, isHijacker)
+
+			w.(http.Flusher).Flush()
+		},
+		expectedResult: []string{`"Status":200`, `"ResponseBytes":0`},
+	},
+}
+
+func TestHTTPLogMiddleware(t *testing.T) {
+	for _, testCase := range logMiddlewareTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actualResult, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "InfoLevel"})
+			defer restore()
+
+			handler := yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				testCase.handler(t, w, r)
+			}), yawhg.HTTPLogMiddleware)
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+			for _, result := range testCase.expectedResult {
+				assert.Contains(t, actualResult.String(), result)
+			}
+		})
+	}
+}
+
+func TestHTTPLogMiddlewarePanic(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "InfoLevel"})
+	defer restore()
+
+	handler := yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), yawhg.HTTPLogMiddleware)
+
+	assert.PanicsWithValue(t, "boom", func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+
+	for _, result := range []string{`"Panic":"boom"`, `"Status":500`, `"severity":"error"`} {
+		assert.Contains(t, actualResult.String(), result)
+	}
+}
+
+func TestHTTPLogMiddlewareHijack(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "InfoLevel"})
+	defer restore()
+
+	server := httptest.NewServer(yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+	}), yawhg.HTTPLogMiddleware))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /socket HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+
+	// the completion is logged once the handler has returned
+	assert.Eventually(t, func() bool {
+		return strings.Contains(actualResult.String(), "http request completed")
+	}, time.Second, 10*time.Millisecond)
+	assert.Contains(t, actualResult.String(), `"Hijacked":true`)
+	assert.Contains(t, actualResult.String(), `"Status":101`)
+}
+
+type bodyLogTestCase struct {
+	name           string
+	path           string
+	contentType    string
+	body           string
+	expectedResult []string
+	excludedResult []string
+}
+
+var bodyLogTestCases = []bodyLogTestCase{
+	bodyLogTestCase{
+		name:           "embed_json_body_as_an_object",
+		path:           "/",
+		contentType:    "application/json",
+		body:           `{"name":"yawhg"}`,
+		expectedResult: []string{`"RequestBody":{"name":"yawhg"}`},
+	},
+	bodyLogTestCase{
+		name:           "parse_and_redact_form_body",
+		path:           "/",
+		contentType:    "application/x-www-form-urlencoded",
+		body:           "u=y&password=pw",
+		expectedResult: []string{`"RequestBody":{"password":["REDACTED"],"u":["y"]}`},
+		excludedResult: []string{`"pw"`},
+	},
+	bodyLogTestCase{
+		name:           "drop_partial_value_of_truncated_form_body",
+		path:           "/",
+		contentType:    "application/x-www-form-urlencoded",
+		body:           "user=yawhg&password=secret",
+		expectedResult: []string{`"RequestBody":{"user":["yawhg"]}`, `"RequestBodyTruncated":true`},
+		excludedResult: []string{"passw"},
+	},
+	bodyLogTestCase{
+		name:           "summarise_binary_body",
+		path:           "/",
+		contentType:    "application/octet-stream",
+		body:           "binary",
+		expectedResult: []string{`"RequestBody":{"ContentType":"application/octet-stream","Size":6}`},
+	},
+	bodyLogTestCase{
+		name:           "truncate_large_body",
+		path:           "/",
+		contentType:    "text/plain",
+		body:           strings.Repeat("a", 20),
+		expectedResult: []string{`"RequestBody":"` + strings.Repeat("a", 16) + `"`, `"RequestBodyTruncated":true`},
+	},
+	bodyLogTestCase{
+		name:           "skip_body_of_skipped_paths",
+		path:           "/login/form",
+		contentType:    "text/plain",
+		body:           "secret",
+		excludedResult: []string{"RequestBody", "secret"},
+	},
+}
+
+func TestHTTPLogMiddlewareRequestBody(t *testing.T) {
+	for _, testCase := range bodyLogTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actualResult, restore := captureLogs(yawhg.Options{
+				Enabled:            true,
+				AppVersion:         "test",
+				LogLevel:           "InfoLevel",
+				MaxBodyLogSize:     16,
+				BodyLogSkipPaths:   []string{"/login/*"},
+				RedactedFormFields: []string{"password"},
+			})
+			defer restore()
+
+			handler := yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.body, string(body), "downstream handlers must receive the whole body")
+			}), yawhg.HTTPLogMiddleware)
+
+			req := httptest.NewRequest("POST", testCase.path, strings.NewReader(testCase.body))
+			req.Header.Set("Content-Type", testCase.contentType)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			for _, result := range testCase.expectedResult {
+				assert.Contains(t, actualResult.String(), result)
+			}
+
+			for _, result := range testCase.excludedResult {
+				assert.NotContains(t, actualResult.String(), result)
+			}
 		})
 	}
 }
+
+// fakeTransportStream records the header metadata set by unary interceptors
+type fakeTransportStream struct {
+	header metadata.MD
+}
+
+func (s *fakeTransportStream) Method() string { return "/test.Service/Call" }
+
+func (s *fakeTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *fakeTransportStream) SendHeader(md metadata.MD) error { return nil }
+
+func (s *fakeTransportStream) SetTrailer(md metadata.MD) error { return nil }
+
+func TestGRPCTraceInterceptorResponseHeader(t *testing.T) {
+	stream := &fakeTransportStream{}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(yawhg.RequestIDHeader, testRequestID))
+	ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+	_, err := yawhg.GRPCTraceInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: stream.Method()}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{testRequestID}, stream.header.Get(yawhg.RequestIDHeader))
+}
+
+func TestCanonicalHTTPMiddleware(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "InfoLevel"})
+	defer restore()
+
+	handler := yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		yawhg.Annotate(ctx, "user_id", "u-1")
+		yawhg.Increment(ctx, "cache_misses", 1)
+		yawhg.Increment(ctx, "cache_misses", 2)
+
+		stop := yawhg.StartTimer(ctx, "db_time")
+		stop()
+
+		w.WriteHeader(http.StatusAccepted)
+	}), yawhg.CanonicalHTTPMiddleware, yawhg.HTTPTraceMiddleware)
+
+	req := httptest.NewRequest("GET", "/orders", nil)
+	req.Header.Set(yawhg.RequestIDHeader, testRequestID)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, 1, strings.Count(actualResult.String(), "\n"), "expected a single log line")
+	for _, result := range []string{
+		`"msg":"canonical log line"`,
+		`"user_id":"u-1"`,
+		`"cache_misses":3`,
+		`"db_time":`,
+		`"Status":202`,
+		`"RequestPath":"/orders"`,
+		`"request_id":"` + testRequestID + `"`,
+	} {
+		assert.Contains(t, actualResult.String(), result)
+	}
+}
+
+func TestCanonicalGRPCInterceptor(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "InfoLevel"})
+	defer restore()
+
+	_, err := yawhg.CanonicalGRPCInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		yawhg.Annotate(ctx, "tenant", "acme")
+		return nil, status.Error(codes.Internal, "failed")
+	})
+
+	assert.Equal(t, codes.Internal, status.Code(err))
+	for _, result := range []string{`"tenant":"acme"`, `"StatusCode":"Internal"`, `"severity":"error"`} {
+		assert.Contains(t, actualResult.String(), result)
+	}
+
+	// annotations without a canonical logger are ignored
+	yawhg.Annotate(context.Background(), "ignored", true)
+}
+
+type requestBufferTestCase struct {
+	name           string
+	status         int
+	logError       bool
+	expectedResult []string
+	excludedResult []string
+}
+
+var requestBufferTestCases = []requestBufferTestCase{
+	requestBufferTestCase{
+		name:           "discard_debug_logs_of_successful_requests",
+		status:         http.StatusOK,
+		excludedResult: []string{"debug trail"},
+	},
+	requestBufferTestCase{
+		name:           "write_debug_logs_of_failed_requests",
+		status:         http.StatusBadGateway,
+		expectedResult: []string{`"msg":"debug trail 1"`, `"msg":"debug trail 2"`, `"msg":"debug trail 3"`},
+	},
+	requestBufferTestCase{
+		name:           "write_debug_logs_before_errors",
+		status:         http.StatusOK,
+		logError:       true,
+		expectedResult: []string{`"msg":"debug trail 3"`, `"msg":"failure"`},
+	},
+}
+
+func TestHTTPTraceMiddlewareBufferRequestLogs(t *testing.T) {
+	for _, testCase := range requestBufferTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actualResult, restore := captureLogs(yawhg.Options{
+				Enabled:           true,
+				AppVersion:        "test",
+				LogLevel:          "InfoLevel",
+				BufferRequestLogs: true,
+				RequestBufferSize: 3,
+			})
+			defer restore()
+
+			handler := yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for i := 0; i <= 3; i++ {
+					yawhg.Debugft(r.Context(), "debug trail %d", i)
+				}
+
+				if testCase.logError {
+					yawhg.ErrorWithTracing(r.Context(), yawhg.Fields{"msg": "failure"})
+				}
+
+				w.WriteHeader(testCase.status)
+			}), yawhg.HTTPTraceMiddleware)
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+			// only the last logs of the request are buffered
+			assert.NotContains(t, actualResult.String(), `"msg":"debug trail 0"`)
+
+			for _, result := range testCase.expectedResult {
+				assert.Contains(t, actualResult.String(), result)
+			}
+
+			for _, result := range testCase.excludedResult {
+				assert.NotContains(t, actualResult.String(), result)
+			}
+
+			if testCase.logError {
+				assert.True(t, strings.Index(actualResult.String(), "debug trail 3") < strings.Index(actualResult.String(), "failure"))
+			}
+		})
+	}
+}
+
+func TestHTTPTraceMiddlewareBufferSharedRequestID(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{
+		Enabled:           true,
+		AppVersion:        "test",
+		LogLevel:          "InfoLevel",
+		BufferRequestLogs: true,
+	})
+	defer restore()
+
+	started := make(chan struct{})
+	finished := make(chan struct{})
+	handler := yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		yawhg.Debugft(r.Context(), "debug %s", r.URL.Path)
+
+		if r.URL.Path == "/a" {
+			close(started)
+			<-finished
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}), yawhg.HTTPTraceMiddleware)
+
+	failed := make(chan struct{})
+	go func() {
+		defer close(failed)
+		req := httptest.NewRequest("GET", "/a", nil)
+		req.Header.Set(yawhg.RequestIDHeader, testRequestID)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	// the successful request ends while the failed one with the same request ID is in flight
+	<-started
+	req := httptest.NewRequest("GET", "/b", nil)
+	req.Header.Set(yawhg.RequestIDHeader, testRequestID)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	close(finished)
+	<-failed
+
+	assert.Contains(t, actualResult.String(), `"msg":"debug /a"`)
+	assert.NotContains(t, actualResult.String(), `"msg":"debug /b"`)
+}
+
+const testLevelOverrideSecret string = "override-secret"
+
+var levelOverrideTestCases = []struct {
+	name          string
+	override      string
+	expectedDebug bool
+}{
+	{
+		name:          "lower_the_level_with_a_valid_override",
+		override:      yawhg.SignLevelOverride(yawhg.DebugLevel, time.Now().Add(time.Hour), testLevelOverrideSecret),
+		expectedDebug: true,
+	},
+	{
+		name:     "ignore_an_expired_override",
+		override: yawhg.SignLevelOverride(yawhg.DebugLevel, time.Now().Add(-time.Minute), testLevelOverrideSecret),
+	},
+	{
+		name:     "ignore_an_override_signed_with_another_secret",
+		override: yawhg.SignLevelOverride(yawhg.DebugLevel, time.Now().Add(time.Hour), "other-secret"),
+	},
+	{
+		name:     "ignore_an_unsigned_override",
+		override: "debug",
+	},
+}
+
+func TestHTTPTraceMiddlewareLevelOverride(t *testing.T) {
+	for _, testCase := range levelOverrideTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actualResult, restore := captureLogs(yawhg.Options{
+				Enabled:             true,
+				AppVersion:          "test",
+				LogLevel:            "InfoLevel",
+				LevelOverrideSecret: testLevelOverrideSecret,
+				LevelOverrideHosts:  []string{"127.0.0.1"},
+			})
+			defer restore()
+
+			var forwarded string
+			handler := yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				yawhg.Debugft(r.Context(), "debug detail")
+
+				downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					forwarded = r.Header.Get(yawhg.LevelOverrideHeader)
+				}))
+				defer downstream.Close()
+
+				req, _ := http.NewRequest("GET", downstream.URL, nil)
+				resp, err := (&http.Client{Transport: yawhg.Transport(nil)}).Do(req.WithContext(r.Context()))
+				if assert.NoError(t, err) {
+					resp.Body.Close()
+				}
+			}), yawhg.HTTPTraceMiddleware)
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set(yawhg.LevelOverrideHeader, testCase.override)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if testCase.expectedDebug {
+				assert.Contains(t, actualResult.String(), `"msg":"debug detail"`)
+				assert.Equal(t, testCase.override, forwarded)
+			} else {
+				assert.NotContains(t, actualResult.String(), `"msg":"debug detail"`)
+				assert.Empty(t, forwarded)
+			}
+		})
+	}
+}
+
+func TestLevelOverrideScope(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{
+		Enabled:             true,
+		AppVersion:          "test",
+		LogLevel:            "InfoLevel",
+		LevelOverrideSecret: testLevelOverrideSecret,
+	})
+	defer restore()
+
+	fields := yawhg.Fields{"msg": "request detail"}
+	var logger *yawhg.Fields
+	handler := yawhg.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		yawhg.InfoWithTracing(r.Context(), fields)
+		logger = yawhg.WithTracing(r.Context(), yawhg.Fields{})
+	}), yawhg.HTTPTraceMiddleware)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(yawhg.LevelOverrideHeader, yawhg.SignLevelOverride(yawhg.DebugLevel, time.Now().Add(time.Hour), testLevelOverrideSecret))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// the request context is neither added to the fields of the caller nor kept by the logger
+	for _, logged := range []yawhg.Fields{fields, *logger} {
+		for _, value := range logged {
+			_, isContext := value.(context.Context)
+			assert.False(t, isContext)
+		}
+	}
+
+	logger.Debug("after the request")
+	yawhg.Debug(fields)
+	assert.Contains(t, actualResult.String(), `"msg":"request detail"`)
+	assert.NotContains(t, actualResult.String(), "after the request")
+	assert.Equal(t, 1, strings.Count(actualResult.String(), "request detail"))
+}
+
+func TestGRPCTraceInterceptorLevelOverride(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{
+		Enabled:             true,
+		AppVersion:          "test",
+		LogLevel:            "InfoLevel",
+		LevelOverrideSecret: testLevelOverrideSecret,
+	})
+	defer restore()
+
+	override := yawhg.SignLevelOverride(yawhg.DebugLevel, time.Now().Add(time.Hour), testLevelOverrideSecret)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(yawhg.LevelOverrideHeader, override))
+
+	_, err := yawhg.GRPCTraceInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		yawhg.Debugft(ctx, "debug detail")
+
+		md, _ := metadata.FromOutgoingContext(ctx)
+		assert.Empty(t, md.Get(yawhg.LevelOverrideHeader), "the override is only forwarded by the client interceptors")
+		return nil, nil
+	})
+
+	assert.NoError(t, err)
+	assert.Contains(t, actualResult.String(), `"msg":"debug detail"`)
+}
@@ -0,0 +1,44 @@
+package yawhg
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// otelSpanContext returns the span context of the active OpenTelemetry span in the context, if any.
+// Its parent span is unknown, since OpenTelemetry span contexts do not carry it
+func otelSpanContext(ctx context.Context) (SpanContext, bool) {
+	otelSpan := trace.SpanContextFromContext(ctx)
+	if !otelSpan.IsValid() {
+		return SpanContext{}, false
+	}
+
+	return SpanContext{
+		TraceID:    otelSpan.TraceID().String(),
+		SpanID:     otelSpan.SpanID().String(),
+		Sampled:    otelSpan.IsSampled(),
+		TraceState: otelSpan.TraceState().String(),
+	}, true
+}
+
+// recordSpanEvent adds the fields as an event of the active OpenTelemetry span in the context, if it is recording
+func recordSpanEvent(ctx context.Context, f *Fields) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	attributes := make([]attribute.KeyValue, 0, len(*f))
+	for key, value := range *f {
+		attributes = append(attributes, attribute.String(key, formatValue(value)))
+	}
+
+	name, ok := (*f)["msg"].(string)
+	if !ok || name == "" {
+		name = "log"
+	}
+
+	span.AddEvent(name, trace.WithAttributes(attributes...))
+}
@@ -0,0 +1,136 @@
+package yawhg
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// LevelOverrideHeader is the header and metadata key of a signed level override for a single request,
+// see SignLevelOverride
+const LevelOverrideHeader string = "x-yawhg-level"
+
+type levelOverrideKey struct{}
+
+// levelOverride is the log level of a single request, along with the signed header which enabled it
+type levelOverride struct {
+	level Level
+	token string
+}
+
+// SignLevelOverride creates the value of the x-yawhg-level header, which sets the log level of a single request until
+// the expiry. The secret must match Options.LevelOverrideSecret of the services receiving the request
+func SignLevelOverride(level Level, expiry time.Time, secret string) string {
+	payload := level.String() + ";" + strconv.FormatInt(expiry.Unix(), 10)
+	return payload + ";" + levelOverrideSignature(payload, []byte(secret))
+}
+
+func levelOverrideSignature(payload string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseLevelOverride verifies the signature and expiry of a level override header
+func parseLevelOverride(token string, secret []byte) (Level, bool) {
+	if len(secret) == 0 || token == "" {
+		return 0, false
+	}
+
+	i := strings.LastIndex(token, ";")
+	if i < 0 {
+		return 0, false
+	}
+
+	payload, signature := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(levelOverrideSignature(payload, secret))) {
+		return 0, false
+	}
+
+	parts := strings.Split(payload, ";")
+	if len(parts) != 2 {
+		return 0, false
+	}
+
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expiry {
+		return 0, false
+	}
+
+	level, err := ParseLevel(parts[0])
+	if err != nil {
+		return 0, false
+	}
+
+	return level, true
+}
+
+// withLevelOverride attaches a valid level override to the context. Invalid and expired overrides are ignored
+func withLevelOverride(ctx context.Context, cfg *runtimeConfig, token string) context.Context {
+	level, ok := parseLevelOverride(token, cfg.levelOverrideSecret)
+	if !ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, levelOverrideKey{}, levelOverride{level: level, token: token})
+}
+
+// forwardsLevelOverride reports whether the host, with or without its port, is allowed to receive level overrides
+func (cfg *runtimeConfig) forwardsLevelOverride(host string) bool {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+
+	for _, allowed := range cfg.levelOverrideHosts {
+		if allowed == host || allowed == hostname {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forwardLevelOverride adds the level override of the context to the outgoing metadata of a call, if the target of the
+// connection is allowed to receive it
+func forwardLevelOverride(ctx context.Context, cfg *runtimeConfig, cc *grpc.ClientConn) context.Context {
+	override, ok := ctx.Value(levelOverrideKey{}).(levelOverride)
+	if !ok || cc == nil {
+		return ctx
+	}
+
+	// targets may carry a resolver scheme, e.g. "dns:///billing:443"
+	target := cc.Target()
+	if i := strings.LastIndex(target, "/"); i >= 0 {
+		target = target[i+1:]
+	}
+	if !cfg.forwardsLevelOverride(target) {
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, LevelOverrideHeader, override.token)
+}
+
+// addLevelOverrideToHeader attaches the level override in the header of the request to the context
+func addLevelOverrideToHeader(ctx context.Context, cfg *runtimeConfig, r *http.Request) context.Context {
+	return withLevelOverride(ctx, cfg, r.Header.Get(LevelOverrideHeader))
+}
+
+// addLevelOverrideFromMetadata attaches the level override in the incoming metadata to the context
+func addLevelOverrideFromMetadata(ctx context.Context, cfg *runtimeConfig) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	return withLevelOverride(ctx, cfg, mdCarrier(md).get(LevelOverrideHeader))
+}
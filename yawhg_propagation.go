@@ -0,0 +1,184 @@
+package yawhg
+
+import (
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// Propagation formats of Options.ExtractFormats and Options.InjectFormats
+const (
+	// RequestIDFormat is the x-request-id header
+	RequestIDFormat string = "x-request-id"
+	// W3CFormat is the W3C Trace Context traceparent and tracestate headers
+	W3CFormat string = "w3c"
+	// B3Format is the Zipkin B3 single b3 header
+	B3Format string = "b3"
+	// B3MultiFormat is the Zipkin B3 X-B3-* headers
+	B3MultiFormat string = "b3multi"
+)
+
+// Zipkin B3 headers, see https://github.com/openzipkin/b3-propagation
+const B3Header string = "b3"
+const B3TraceIDHeader string = "X-B3-TraceId"
+const B3SpanIDHeader string = "X-B3-SpanId"
+const B3ParentSpanIDHeader string = "X-B3-ParentSpanId"
+const B3SampledHeader string = "X-B3-Sampled"
+const B3FlagsHeader string = "X-B3-Flags"
+
+// defaultFormats are extracted and injected unless configured otherwise
+var defaultFormats = []string{RequestIDFormat, W3CFormat}
+
+// propagator reads and writes span contexts in a propagation format
+type propagator interface {
+	extract(c carrier) (SpanContext, bool)
+	inject(c carrier, sc SpanContext)
+	fields() []string
+}
+
+// propagators of the span context by format. The x-request-id format carries no span context
+var propagators = map[string]propagator{
+	W3CFormat:     w3cPropagator{},
+	B3Format:      b3Propagator{},
+	B3MultiFormat: b3MultiPropagator{},
+}
+
+// extractSpan reads the span context of the first of the formats present in the carrier
+func extractSpan(c carrier, formats []string) (SpanContext, bool) {
+	for _, format := range formats {
+		if p, ok := propagators[format]; ok {
+			if sc, ok := p.extract(c); ok {
+				return sc, true
+			}
+		}
+	}
+
+	return SpanContext{}, false
+}
+
+// injectSpan writes the span context to the carrier in each of the formats
+func injectSpan(c carrier, sc SpanContext, formats []string) {
+	for _, format := range formats {
+		if p, ok := propagators[format]; ok {
+			p.inject(c, sc)
+		}
+	}
+}
+
+func hasFormat(formats []string, format string) bool {
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+
+	return false
+}
+
+// carrier abstracts over http headers and gRPC metadata for the propagation of span contexts
+type carrier interface {
+	get(key string) string
+	set(key, value string)
+}
+
+type headerCarrier http.Header
+
+func (c headerCarrier) get(key string) string {
+	return http.Header(c).Get(key)
+}
+
+func (c headerCarrier) set(key, value string) {
+	http.Header(c).Set(key, value)
+}
+
+type mdCarrier metadata.MD
+
+func (c mdCarrier) get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func (c mdCarrier) set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// b3Propagator reads and writes the single b3 header: {TraceId}-{SpanId}-{SamplingState}-{ParentSpanId}
+type b3Propagator struct{}
+
+func (b3Propagator) extract(c carrier) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(c.get(B3Header)), "-")
+	if len(parts) < 2 || len(parts) > 4 {
+		return SpanContext{}, false // also rejects a lone sampling state, which carries no span to continue
+	}
+
+	sampled := ""
+	if len(parts) > 2 {
+		sampled = parts[2]
+	}
+
+	return parseB3(parts[0], parts[1], sampled, "")
+}
+
+func (b3Propagator) inject(c carrier, sc SpanContext) {
+	value := sc.TraceID + "-" + sc.SpanID + "-" + b3Sampled(sc.Sampled)
+	if sc.ParentSpanID != "" {
+		value += "-" + sc.ParentSpanID
+	}
+
+	c.set(B3Header, value)
+}
+
+func (b3Propagator) fields() []string {
+	return []string{B3Header}
+}
+
+// b3MultiPropagator reads and writes the X-B3-* headers
+type b3MultiPropagator struct{}
+
+func (b3MultiPropagator) extract(c carrier) (SpanContext, bool) {
+	return parseB3(c.get(B3TraceIDHeader), c.get(B3SpanIDHeader), c.get(B3SampledHeader), c.get(B3FlagsHeader))
+}
+
+func (b3MultiPropagator) inject(c carrier, sc SpanContext) {
+	c.set(B3TraceIDHeader, sc.TraceID)
+	c.set(B3SpanIDHeader, sc.SpanID)
+	if sc.ParentSpanID != "" {
+		c.set(B3ParentSpanIDHeader, sc.ParentSpanID)
+	}
+	c.set(B3SampledHeader, b3Sampled(sc.Sampled))
+}
+
+func (b3MultiPropagator) fields() []string {
+	return []string{B3TraceIDHeader, B3SpanIDHeader, B3ParentSpanIDHeader, B3SampledHeader, B3FlagsHeader}
+}
+
+// parseB3 validates the B3 identifiers, widening 64 bit trace IDs to 128 bits.
+// The sampling decision defaults to sampled when it is deferred, and debug implies sampled
+func parseB3(traceID, spanID, sampled, flags string) (SpanContext, bool) {
+	traceID, spanID = strings.ToLower(traceID), strings.ToLower(spanID)
+	if isHex(traceID, 16) {
+		traceID = strings.Repeat("0", 16) + traceID
+	}
+
+	if !isHex(traceID, 32) || !isHex(spanID, 16) || isZero(traceID) || isZero(spanID) {
+		return SpanContext{}, false
+	}
+
+	return SpanContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: sampled != "0" && sampled != "false" || flags == "1",
+	}, true
+}
+
+func b3Sampled(sampled bool) string {
+	if sampled {
+		return "1"
+	}
+
+	return "0"
+}
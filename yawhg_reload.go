@@ -0,0 +1,103 @@
+package yawhg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultWatchInterval is the interval at which WatchConfigFile checks the config file for changes by default
+const defaultWatchInterval = time.Second
+
+// WatchConfigFile configures yawhg from a config file like ConfigFromFile, then reloads it whenever its content changes
+// or the process receives SIGHUP, until stop is called. The file is checked for changes at the interval, every second
+// by default. Each reload is logged, and a config which fails to reload is logged as an error and leaves the previous
+// configuration in place. The file should be replaced atomically, e.g. by renaming, so that it is never read while
+// partially written
+func WatchConfigFile(path string, interval time.Duration) (stop func(), err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading yawhg config: %v", err)
+	}
+
+	c, err := parseConfig(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.apply(); err != nil {
+		return nil, err
+	}
+
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	w := &configWatcher{path: path, data: data, done: make(chan struct{})}
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		defer signal.Stop(hangup)
+
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-ticker.C:
+				w.reload(false)
+			case <-hangup:
+				w.reload(true)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(w.done) }) }, nil
+}
+
+// configWatcher reloads a config file, keeping its last content to detect changes
+type configWatcher struct {
+	path       string
+	data       []byte
+	unreadable bool
+	done       chan struct{}
+}
+
+// reload applies the config file if its content changed since the last reload, or unconditionally if forced
+func (w *configWatcher) reload(force bool) {
+	data, err := ioutil.ReadFile(w.path)
+	if err != nil {
+		// a missing file is reported once, rather than on every check until it is restored
+		if !w.unreadable || force {
+			WithFields(Fields{"Path": w.path}, err).log(context.Background(), currentConfig(), ErrorLevel, "reloading yawhg config")
+		}
+		w.unreadable = true
+		return
+	}
+	w.unreadable = false
+
+	if !force && bytes.Equal(data, w.data) {
+		return
+	}
+	// a config which fails to reload is reported once, rather than on every check until it changes
+	w.data = data
+
+	c, err := parseConfig(w.path, data)
+	if err == nil {
+		err = c.apply()
+	}
+	if err != nil {
+		WithFields(Fields{"Path": w.path}, err).log(context.Background(), currentConfig(), ErrorLevel, "reloading yawhg config")
+		return
+	}
+
+	WithFields(Fields{"Path": w.path, "Level": GetLevel().String()}).log(context.Background(), currentConfig(), InfoLevel, "yawhg config reloaded")
+}
@@ -0,0 +1,114 @@
+package yawhg
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math/big"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// maxRequestIDLength is the maximum length of an incoming request ID accepted by ValidRequestID
+const maxRequestIDLength int = 128
+
+// RequestIDGenerator generates the request ID of requests which arrive without one
+type RequestIDGenerator func() (string, error)
+
+// RequestIDValidator reports whether an incoming request ID may be used. Invalid request IDs are replaced by new ones
+type RequestIDValidator func(requestID string) bool
+
+// NewUUIDv4 generates a random UUID, the default request ID
+func NewUUIDv4() (string, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+
+	return id.String(), nil
+}
+
+// NewUUIDv7 generates a UUID which sorts by its creation time, see RFC 9562
+func NewUUIDv7() (string, error) {
+	var id uuid.UUID
+	if _, err := rand.Read(id[6:]); err != nil {
+		return "", err
+	}
+
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	id[0], id[1], id[2], id[3], id[4], id[5] = byte(ms>>40), byte(ms>>32), byte(ms>>24), byte(ms>>16), byte(ms>>8), byte(ms)
+	id[6] = 0x70 | id[6]&0x0f // version 7
+	id[8] = 0x80 | id[8]&0x3f // variant 10
+
+	return id.String(), nil
+}
+
+// crockfordAlphabet is the base32 alphabet of ULIDs
+const crockfordAlphabet string = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID generates a Universally Unique Lexicographically Sortable Identifier, see https://github.com/ulid/spec
+func NewULID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[6:]); err != nil {
+		return "", err
+	}
+
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	id[0], id[1], id[2], id[3], id[4], id[5] = byte(ms>>40), byte(ms>>32), byte(ms>>24), byte(ms>>16), byte(ms>>8), byte(ms)
+
+	// the 128 bits are encoded as 26 characters of 5 bits each, the first of which only holds the top 3 bits
+	hi, lo := binary.BigEndian.Uint64(id[:8]), binary.BigEndian.Uint64(id[8:])
+	encoded := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		encoded[i] = crockfordAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(encoded), nil
+}
+
+// base62Alphabet is the alphabet of KSUIDs
+const base62Alphabet string = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ksuidEpoch is the epoch of KSUID timestamps, 2014-05-13T16:53:20Z
+const ksuidEpoch int64 = 1400000000
+
+// NewKSUID generates a K-Sortable Unique Identifier, see https://github.com/segmentio/ksuid
+func NewKSUID() (string, error) {
+	var id [20]byte
+	if _, err := rand.Read(id[4:]); err != nil {
+		return "", err
+	}
+
+	binary.BigEndian.PutUint32(id[:4], uint32(time.Now().Unix()-ksuidEpoch))
+
+	n := new(big.Int).SetBytes(id[:])
+	base, digit := big.NewInt(62), new(big.Int)
+	encoded := make([]byte, 27)
+	for i := 26; i >= 0; i-- {
+		n.DivMod(n, base, digit)
+		encoded[i] = base62Alphabet[digit.Int64()]
+	}
+
+	return string(encoded), nil
+}
+
+// ValidRequestID accepts request IDs of up to 128 letters, digits, and - _ . : + / = characters,
+// which guards headers and logs against injection
+func ValidRequestID(requestID string) bool {
+	if len(requestID) == 0 || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range requestID {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '+', c == '/', c == '=':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,216 @@
+package yawhg
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// responseRecorder wraps an http.ResponseWriter to capture the status code and size of the response
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+	// hijacked is set once the connection has been taken over by the handler, whose response is no longer recorded
+	hijacked bool
+}
+
+// WriteHeader records the status code of the response
+func (r *responseRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Write records the number of bytes written to the response
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.markHeaderWritten()
+
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+
+	return n, err
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for use by http.ResponseController
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Status returns the status code of the response, which is http.StatusOK unless set otherwise, or
+// http.StatusSwitchingProtocols if the connection was hijacked before any header was written
+func (r *responseRecorder) Status() int {
+	if r.status == 0 && r.hijacked {
+		return http.StatusSwitchingProtocols
+	}
+	if r.status == 0 {
+		return http.StatusOK
+	}
+
+	return r.status
+}
+
+// markHeaderWritten records the implicit http.StatusOK of a response written without calling WriteHeader
+func (r *responseRecorder) markHeaderWritten() {
+	if !r.wroteHeader {
+		r.status = http.StatusOK
+		r.wroteHeader = true
+	}
+}
+
+// The optional interfaces of the wrapped http.ResponseWriter are implemented by separate types, so that the wrapper
+// returned by wrapResponseWriter implements exactly the interfaces implemented by the original http.ResponseWriter
+type recorderFlusher struct{ *responseRecorder }
+type recorderHijacker struct{ *responseRecorder }
+type recorderPusher struct{ *responseRecorder }
+type recorderReaderFrom struct{ *responseRecorder }
+
+func (r recorderFlusher) Flush() {
+	r.markHeaderWritten()
+	r.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (r recorderHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := r.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		r.hijacked = true
+	}
+
+	return conn, rw, err
+}
+
+func (r recorderPusher) Push(target string, opts *http.PushOptions) error {
+	return r.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+func (r recorderReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	r.markHeaderWritten()
+
+	n, err := r.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	r.bytes += n
+
+	return n, err
+}
+
+// wrapResponseWriter returns an http.ResponseWriter recording the status code and size of the response,
+// which keeps http.Flusher, http.Hijacker, http.Pusher and io.ReaderFrom working if the original supports them
+func wrapResponseWriter(w http.ResponseWriter) (http.ResponseWriter, *responseRecorder) {
+	r := &responseRecorder{ResponseWriter: w}
+	f, h, p, rf := recorderFlusher{r}, recorderHijacker{r}, recorderPusher{r}, recorderReaderFrom{r}
+
+	var supported int
+	if _, ok := w.(http.Flusher); ok {
+		supported |= 1
+	}
+	if _, ok := w.(http.Hijacker); ok {
+		supported |= 2
+	}
+	if _, ok := w.(http.Pusher); ok {
+		supported |= 4
+	}
+	if _, ok := w.(io.ReaderFrom); ok {
+		supported |= 8
+	}
+
+	switch supported {
+	case 1:
+		return struct {
+			*responseRecorder
+			http.Flusher
+		}{r, f}, r
+	case 2:
+		return struct {
+			*responseRecorder
+			http.Hijacker
+		}{r, h}, r
+	case 3:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			http.Hijacker
+		}{r, f, h}, r
+	case 4:
+		return struct {
+			*responseRecorder
+			http.Pusher
+		}{r, p}, r
+	case 5:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			http.Pusher
+		}{r, f, p}, r
+	case 6:
+		return struct {
+			*responseRecorder
+			http.Hijacker
+			http.Pusher
+		}{r, h, p}, r
+	case 7:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{r, f, h, p}, r
+	case 8:
+		return struct {
+			*responseRecorder
+			io.ReaderFrom
+		}{r, rf}, r
+	case 9:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			io.ReaderFrom
+		}{r, f, rf}, r
+	case 10:
+		return struct {
+			*responseRecorder
+			http.Hijacker
+			io.ReaderFrom
+		}{r, h, rf}, r
+	case 11:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{r, f, h, rf}, r
+	case 12:
+		return struct {
+			*responseRecorder
+			http.Pusher
+			io.ReaderFrom
+		}{r, p, rf}, r
+	case 13:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			http.Pusher
+			io.ReaderFrom
+		}{r, f, p, rf}, r
+	case 14:
+		return struct {
+			*responseRecorder
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{r, h, p, rf}, r
+	case 15:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{r, f, h, p, rf}, r
+	}
+
+	return r, r
+}
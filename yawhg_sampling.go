@@ -0,0 +1,234 @@
+package yawhg
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// defaultSamplingInterval is the interval over which the logs are counted for sampling by default
+const defaultSamplingInterval = time.Second
+
+// maxSampledMessages bounds the messages counted per interval, beyond which the logs of a level are counted together
+const maxSampledMessages int = 10000
+
+const samplingMessage string = "log entries suppressed by sampling"
+
+// SamplingRule samples the logs of a level, or of a message at a level. Up to First logs are written per interval,
+// then every Thereafter-th log, or none if Thereafter is 0. An empty Level or Message matches any.
+// The message of logs without one, such as those of GRPCLogInterceptor, is their Method field
+type SamplingRule struct {
+	Level      string `yaml:"level" json:"level"`
+	Message    string `yaml:"message" json:"message"`
+	First      int    `yaml:"first" json:"first"`
+	Thereafter int    `yaml:"thereafter" json:"thereafter"`
+}
+
+// samplingKey identifies the logs which are counted together
+type samplingKey struct {
+	level Level
+	msg   string
+}
+
+type sampleCount struct {
+	seen       int
+	suppressed int
+}
+
+// logSampler counts the logs matching its rules over each interval to decide which are written, and reports the
+// suppressed logs at the end of every interval
+type logSampler struct {
+	rules       []samplingRule
+	interval    time.Duration
+	byRequestID bool
+
+	mu      sync.Mutex
+	counts  map[samplingKey]*sampleCount
+	stopped bool
+
+	ticker *time.Ticker
+	done   chan struct{}
+	exited chan struct{}
+}
+
+type samplingRule struct {
+	SamplingRule
+	level    Level
+	anyLevel bool
+}
+
+// newSampler returns a sampler for the rules, or nil if there are none. Rules with an invalid level, which are reported
+// by Options.Validate, are disabled rather than applied to every level
+func newSampler(rules []SamplingRule, interval time.Duration, byRequestID bool) *logSampler {
+	var valid []samplingRule
+	for _, rule := range rules {
+		if rule.Level == "" {
+			valid = append(valid, samplingRule{SamplingRule: rule, anyLevel: true})
+			continue
+		}
+
+		level, err := parseOptionLevel(rule.Level)
+		if err != nil {
+			continue
+		}
+		valid = append(valid, samplingRule{SamplingRule: rule, level: level})
+	}
+
+	if len(valid) == 0 {
+		return nil
+	}
+
+	if interval <= 0 {
+		interval = defaultSamplingInterval
+	}
+
+	s := &logSampler{
+		rules:       valid,
+		interval:    interval,
+		byRequestID: byRequestID,
+		counts:      map[samplingKey]*sampleCount{},
+		ticker:      time.NewTicker(interval),
+		done:        make(chan struct{}),
+		exited:      make(chan struct{}),
+	}
+	go s.run()
+
+	return s
+}
+
+// run reports the suppressed logs at the end of every interval, until the sampler is stopped
+func (s *logSampler) run() {
+	defer close(s.exited)
+
+	for {
+		select {
+		case <-s.ticker.C:
+			s.report()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// stop reports the logs suppressed over the current interval, once the sampler has been replaced by ConfigYawhg.
+// The logs sampled by a stopped sampler are written
+func (s *logSampler) stop() {
+	s.ticker.Stop()
+	close(s.done)
+	<-s.exited
+
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+
+	s.report()
+}
+
+// report writes the reports of the logs suppressed over the current interval, and starts a new interval
+func (s *logSampler) report() {
+	s.mu.Lock()
+	reports := s.rollover()
+	s.mu.Unlock()
+
+	cfg := currentConfig()
+	for _, report := range reports {
+		report.report(cfg)
+	}
+}
+
+// match returns the first rule matching the logs of a message at a level, if any
+func (s *logSampler) match(level Level, msg string) (samplingRule, bool) {
+	for _, rule := range s.rules {
+		if (rule.anyLevel || rule.level == level) && (rule.Message == "" || rule.Message == msg) {
+			return rule, true
+		}
+	}
+
+	return samplingRule{}, false
+}
+
+// sample reports whether the fields are written
+func (s *logSampler) sample(f *Fields, level Level) bool {
+	msg := samplingMessageKey(f)
+	rule, ok := s.match(level, msg)
+	if !ok {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return true
+	}
+
+	key := samplingKey{level: level, msg: msg}
+	count, ok := s.counts[key]
+	if !ok {
+		if len(s.counts) >= maxSampledMessages {
+			key.msg = ""
+		}
+		if count, ok = s.counts[key]; !ok {
+			count = &sampleCount{}
+			s.counts[key] = count
+		}
+	}
+	count.seen++
+
+	var written bool
+	if requestID, ok := (*f)[requestIDKey].(string); ok && s.byRequestID && requestID != "" {
+		// every log of a request is either written or suppressed, so that sampled requests are complete
+		written = rule.Thereafter > 0 && requestHash(requestID)%uint32(rule.Thereafter) == 0
+	} else {
+		written = count.seen <= rule.First || rule.Thereafter > 0 && (count.seen-rule.First)%rule.Thereafter == 0
+	}
+
+	if !written {
+		count.suppressed++
+	}
+
+	return written
+}
+
+// rollover starts a new interval, returning the reports of the logs suppressed over the current one. s.mu must be held
+func (s *logSampler) rollover() []*Fields {
+	var reports []*Fields
+	for key, count := range s.counts {
+		if count.suppressed == 0 {
+			continue
+		}
+
+		report := Fields{
+			"severity":   key.level.String(),
+			"msg":        samplingMessage,
+			"Suppressed": count.suppressed,
+			"Interval":   s.interval.String(),
+		}
+		if key.msg != "" {
+			report["SampledMsg"] = key.msg
+		}
+		reports = append(reports, &report)
+	}
+
+	s.counts = map[samplingKey]*sampleCount{}
+
+	return reports
+}
+
+// samplingMessageKey returns the message which identifies the logs counted together, which is the Method of the logs
+// of the gRPC middleware since they have none
+func samplingMessageKey(f *Fields) string {
+	if msg, ok := (*f)["msg"].(string); ok {
+		return msg
+	}
+
+	method, _ := (*f)["Method"].(string)
+	return method
+}
+
+// requestHash distributes request IDs uniformly for sampling
+func requestHash(requestID string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(requestID))
+	return h.Sum32()
+}
@@ -0,0 +1,176 @@
+package yawhg_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MarcvanMelle/yawhg"
+)
+
+var samplingTestCases = []struct {
+	name            string
+	rules           []yawhg.SamplingRule
+	sampledLog      string
+	expectedWritten int
+}{
+	{
+		name:            "write_the_first_n_then_every_mth_log",
+		rules:           []yawhg.SamplingRule{{Level: "info", Message: "hot path", First: 2, Thereafter: 3}},
+		sampledLog:      `"msg":"hot path"`,
+		expectedWritten: 4, // the 1st, 2nd, 5th and 8th
+	},
+	{
+		name:            "write_only_the_first_n_logs_without_thereafter",
+		rules:           []yawhg.SamplingRule{{Message: "hot path", First: 3}},
+		sampledLog:      `"msg":"hot path"`,
+		expectedWritten: 3,
+	},
+	{
+		name:            "match_logs_without_a_message_by_method",
+		rules:           []yawhg.SamplingRule{{Message: "/test.Service/Call", First: 1}},
+		sampledLog:      `"Method":"/test.Service/Call"`,
+		expectedWritten: 1,
+	},
+	{
+		name:            "ignore_logs_of_other_levels",
+		rules:           []yawhg.SamplingRule{{Level: "error", First: 1}},
+		sampledLog:      `"msg":"hot path"`,
+		expectedWritten: 10,
+	},
+	{
+		name:            "ignore_logs_of_other_messages",
+		rules:           []yawhg.SamplingRule{{Message: "cold path", First: 1}},
+		sampledLog:      `"msg":"hot path"`,
+		expectedWritten: 10,
+	},
+}
+
+func TestSampling(t *testing.T) {
+	for _, testCase := range samplingTestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actualResult, restore := captureLogs(yawhg.Options{
+				Enabled:          true,
+				AppVersion:       "test",
+				LogLevel:         "InfoLevel",
+				SamplingRules:    testCase.rules,
+				SamplingInterval: time.Hour,
+			})
+			defer restore()
+
+			for i := 0; i < 10; i++ {
+				yawhg.WithFields(yawhg.Fields{"i": i}).Info("hot path")
+				yawhg.Infow(yawhg.Fields{"Method": "/test.Service/Call"})
+			}
+
+			assert.Equal(t, testCase.expectedWritten, strings.Count(actualResult.String(), testCase.sampledLog))
+		})
+	}
+}
+
+func TestSamplingReport(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{
+		Enabled:          true,
+		AppVersion:       "test",
+		LogLevel:         "InfoLevel",
+		SamplingRules:    []yawhg.SamplingRule{{Message: "hot path", First: 1}},
+		SamplingInterval: 20 * time.Millisecond,
+	})
+	defer restore()
+
+	for i := 0; i < 5; i++ {
+		yawhg.WithFields(yawhg.Fields{}).Info("hot path")
+	}
+
+	// the report is written at the end of the interval, without waiting for the next log
+	assert.Eventually(t, func() bool {
+		return strings.Contains(actualResult.String(), `"Interval":"20ms","SampledMsg":"hot path","Suppressed":4,"msg":"log entries suppressed by sampling","severity":"info"`)
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 1, strings.Count(actualResult.String(), `"msg":"hot path"`))
+	assert.Equal(t, 1, strings.Count(actualResult.String(), samplingReport))
+}
+
+const samplingReport string = `"msg":"log entries suppressed by sampling"`
+
+func TestSamplingReportOnReload(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{
+		Enabled:          true,
+		AppVersion:       "test",
+		LogLevel:         "InfoLevel",
+		SamplingRules:    []yawhg.SamplingRule{{Message: "hot path", First: 1}},
+		SamplingInterval: time.Hour,
+	})
+
+	for i := 0; i < 5; i++ {
+		yawhg.WithFields(yawhg.Fields{}).Info("hot path")
+	}
+	assert.NotContains(t, actualResult.String(), samplingReport)
+
+	// the suppressed logs of the replaced configuration are reported
+	restore()
+	assert.Contains(t, actualResult.String(), `"Suppressed":4,"msg":"log entries suppressed by sampling"`)
+}
+
+func TestSamplingRuleInvalidLevel(t *testing.T) {
+	var handled []string
+	actualResult, restore := captureLogs(yawhg.Options{
+		Enabled:    true,
+		AppVersion: "test",
+		LogLevel:   "InfoLevel",
+		SamplingRules: []yawhg.SamplingRule{
+			{Level: "warn", First: 1},
+			{Level: "info", Message: "hot path", First: 1},
+		},
+		SamplingInterval: time.Hour,
+		ErrorHandler: func(err error) {
+			handled = append(handled, err.Error())
+		},
+	})
+	defer restore()
+
+	for i := 0; i < 5; i++ {
+		yawhg.Error("failure")
+		yawhg.WithFields(yawhg.Fields{}).Info("hot path")
+	}
+
+	// the invalid rule is disabled rather than applied to every level, and the valid one still applies
+	assert.Equal(t, 5, strings.Count(actualResult.String(), `"msg":"failure"`))
+	assert.Equal(t, 1, strings.Count(actualResult.String(), `"msg":"hot path"`))
+	if assert.Len(t, handled, 1) {
+		assert.Contains(t, handled[0], "SamplingRules[0]")
+	}
+}
+
+func TestSamplingByRequestID(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{
+		Enabled:           true,
+		AppVersion:        "test",
+		LogLevel:          "InfoLevel",
+		SamplingRules:     []yawhg.SamplingRule{{Level: "info", Thereafter: 2}},
+		SamplingInterval:  time.Hour,
+		SampleByRequestID: true,
+	})
+	defer restore()
+
+	var sampled int
+	for i := 0; i < 20; i++ {
+		requestID := fmt.Sprintf("request-%d", i)
+		ctx := yawhg.AddToContext(context.Background(), requestID)
+		for j := 0; j < 3; j++ {
+			yawhg.Infoft(ctx, "step %d", j)
+		}
+
+		// every log of a request is either written or suppressed
+		written := strings.Count(actualResult.String(), `"request_id":"`+requestID+`"`)
+		assert.Contains(t, []int{0, 3}, written, requestID)
+		if written > 0 {
+			sampled++
+		}
+	}
+
+	assert.True(t, sampled > 0 && sampled < 20, "sampled %d of 20 requests", sampled)
+}
@@ -616,6 +616,37 @@ func TestCumulativeLogger(t *testing.T) {
 	}
 }
 
+func TestContextFields(t *testing.T) {
+	actualResult, restore := captureLogs(yawhg.Options{Enabled: true, AppVersion: "test", LogLevel: "InfoLevel"})
+	defer restore()
+
+	ctx := yawhg.NewContext(context.Background(), yawhg.Fields{"service": "billing"})
+	ctx = yawhg.AddFields(ctx, yawhg.Fields{"user_id": 42})
+	ctx = yawhg.AddFields(ctx, yawhg.Fields{"order_id": "o-1", "user_id": 43})
+
+	yawhg.Infoft(ctx, "template %s", "message")
+	yawhg.InfoWithTracing(ctx, yawhg.Fields{"order_id": "o-2"})
+	yawhg.Ctx(ctx).Info("ctx message")
+
+	lines := strings.Split(strings.TrimSpace(actualResult.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 logs, but got %d", len(lines))
+	}
+
+	for _, line := range lines {
+		for _, result := range []string{`"service":"billing"`, `"user_id":43`} {
+			if !strings.Contains(line, result) {
+				t.Fatalf("expected %v to contain %v", line, result)
+			}
+		}
+	}
+
+	// the fields of the log take precedence over those of the context
+	if !strings.Contains(lines[1], `"order_id":"o-2"`) {
+		t.Fatalf("expected %v to contain %v", lines[1], `"order_id":"o-2"`)
+	}
+}
+
 func TestLogConcurrently(t *testing.T) {
 	yawhg.ConfigYawhg(yawhg.Options{
 		Enabled:    false,
@@ -662,6 +693,46 @@ func TestLogConcurrently(t *testing.T) {
 	})
 }
 
+// syncBuffer is a buffer which is safe for concurrent use, for logs written by background goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLogs configures yawhg with the options, and returns a buffer receiving the logs until restore is called.
+// The Output of the options is replaced, so that logs written in the background are captured too
+func captureLogs(options yawhg.Options) (actualResult *syncBuffer, restore func()) {
+	previousDestination := yawhg.Destination
+
+	actualResult = new(syncBuffer)
+	options.Output = actualResult
+	yawhg.ConfigYawhg(options)
+
+	return actualResult, func() {
+		// the logs held back by the options, such as sampling reports, are captured once they are replaced
+		yawhg.ConfigYawhg(yawhg.Options{Enabled: true, Output: actualResult, LogLevel: "InfoLevel"})
+		yawhg.ConfigYawhg(yawhg.Options{Enabled: true, Output: previousDestination, LogLevel: "InfoLevel"})
+	}
+}
+
 func BenchmarkYawhg(b *testing.B) {
 	yawhg.ConfigYawhg(yawhg.Options{
 		Enabled:    false,
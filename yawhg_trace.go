@@ -0,0 +1,233 @@
+package yawhg
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// W3C Trace Context headers, see https://www.w3.org/TR/trace-context/
+const TraceparentHeader string = "traceparent"
+const TracestateHeader string = "tracestate"
+
+const traceIDKey string = "trace_id"
+const spanIDKey string = "span_id"
+const parentSpanIDKey string = "parent_span_id"
+
+// SpanContext identifies the current hop of a request through the service mesh
+type SpanContext struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Sampled      bool
+	TraceState   string
+}
+
+type spanContextKey struct{}
+
+// SpanFromContext retrieves the span of the current hop of the context, if any, which is the active OpenTelemetry span,
+// or else the span attached by the trace middleware and interceptors or ContextWithSpan
+func SpanFromContext(ctx context.Context) (SpanContext, bool) {
+	if sc, ok := otelSpanContext(ctx); ok {
+		return sc, true
+	}
+
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok
+}
+
+// ContextWithSpan attaches the span context to the context, and to the outgoing metadata in the formats of
+// Options.InjectFormats so that it is propagated to downstream services as their parent span
+func ContextWithSpan(ctx context.Context, sc SpanContext) context.Context {
+	return contextWithSpan(ctx, currentConfig(), sc)
+}
+
+func contextWithSpan(ctx context.Context, cfg *runtimeConfig, sc SpanContext) context.Context {
+	ctx = context.WithValue(ctx, spanContextKey{}, sc)
+
+	// replace rather than append, so that a previous span in the outgoing metadata is not propagated instead
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	for _, p := range propagators {
+		for _, key := range p.fields() {
+			delete(md, strings.ToLower(key))
+		}
+	}
+	injectSpan(mdCarrier(md), sc, cfg.injectFormats)
+
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// addSpanToHeader attaches the span of the hop of the request to the context and to the header of a copy of the request,
+// see startSpan
+func addSpanToHeader(ctx context.Context, cfg *runtimeConfig, r *http.Request) (context.Context, *http.Request) {
+	sc := startSpan(ctx, cfg, headerCarrier(r.Header))
+
+	// https://godoc.org/net/http#Handler - "Except for reading the body, handlers should not modify the provided Request."
+	// We create a shallow copy of the request, update a copy of its header, and return that
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.Header = r.Header.Clone()
+	injectSpan(headerCarrier(r2.Header), sc, cfg.injectFormats)
+
+	return contextWithSpan(ctx, cfg, sc), r2
+}
+
+// addSpanFromMetadata attaches the span of the hop of the call to the context, see startSpan
+func addSpanFromMetadata(ctx context.Context, cfg *runtimeConfig) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return contextWithSpan(ctx, cfg, startSpan(ctx, cfg, mdCarrier(md)))
+}
+
+// addIncomingSpan attaches a child of the parent span found in the incoming metadata in one of the
+// Options.ExtractFormats to a context which has no span, such as the context of a call which did not go through the
+// trace interceptors. Contexts without a parent span are left without a span
+func addIncomingSpan(ctx context.Context, cfg *runtimeConfig) context.Context {
+	if _, ok := SpanFromContext(ctx); ok {
+		return ctx
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	parent, ok := extractSpan(mdCarrier(md), cfg.extractFormats)
+	if !ok {
+		return ctx
+	}
+
+	return contextWithSpan(ctx, cfg, parent.child())
+}
+
+// startSpan returns the span of the hop of an incoming request, which is the active OpenTelemetry span, a child of the
+// parent span found in the carrier in one of the Options.ExtractFormats, or else the root span of a new trace.
+// It is called once per request by the trace middleware and interceptors, so that every log of the request has the
+// same span
+func startSpan(ctx context.Context, cfg *runtimeConfig, c carrier) SpanContext {
+	if sc, ok := otelSpanContext(ctx); ok {
+		return sc
+	}
+
+	if parent, ok := extractSpan(c, cfg.extractFormats); ok {
+		return parent.child()
+	}
+
+	return newRootSpan()
+}
+
+// newRootSpan starts a new sampled trace
+func newRootSpan() SpanContext {
+	return SpanContext{
+		TraceID: randomHex(16),
+		SpanID:  randomHex(8),
+		Sampled: true,
+	}
+}
+
+// child returns the span context of the next hop, which inherits the trace, sampling decision and trace state
+func (sc SpanContext) child() SpanContext {
+	return SpanContext{
+		TraceID:      sc.TraceID,
+		SpanID:       randomHex(8),
+		ParentSpanID: sc.SpanID,
+		Sampled:      sc.Sampled,
+		TraceState:   sc.TraceState,
+	}
+}
+
+// traceparent formats the span context as a version 00 traceparent header value
+func (sc SpanContext) traceparent() string {
+	var flags byte
+	if sc.Sampled {
+		flags = 1
+	}
+
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, flags)
+}
+
+// parseTraceparent parses a traceparent header value. Values of future versions are parsed as version 00
+func parseTraceparent(value string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, false
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isHex(version, 2) || version == "ff" || !isHex(traceID, 32) || !isHex(spanID, 16) || !isHex(flags, 2) {
+		return SpanContext{}, false
+	}
+
+	if isZero(traceID) || isZero(spanID) {
+		return SpanContext{}, false
+	}
+
+	flagBytes, _ := hex.DecodeString(flags)
+
+	return SpanContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: flagBytes[0]&1 == 1,
+	}, true
+}
+
+// w3cPropagator reads and writes the traceparent and tracestate headers
+type w3cPropagator struct{}
+
+func (w3cPropagator) extract(c carrier) (SpanContext, bool) {
+	sc, ok := parseTraceparent(c.get(TraceparentHeader))
+	if !ok {
+		return SpanContext{}, false
+	}
+
+	sc.TraceState = c.get(TracestateHeader)
+	return sc, true
+}
+
+func (w3cPropagator) inject(c carrier, sc SpanContext) {
+	c.set(TraceparentHeader, sc.traceparent())
+	if sc.TraceState != "" {
+		c.set(TracestateHeader, sc.TraceState)
+	}
+}
+
+func (w3cPropagator) fields() []string {
+	return []string{TraceparentHeader, TracestateHeader}
+}
+
+// randomHex returns n random bytes encoded as lowercase hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	for isZero(hex.EncodeToString(b)) {
+		rand.Read(b)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func isHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
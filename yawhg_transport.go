@@ -0,0 +1,184 @@
+package yawhg
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// redactedValue replaces the values of redacted query parameters and headers in logs
+const redactedValue string = "REDACTED"
+
+type redactionKey struct{}
+
+// defaultRedactedHeaders are never logged by the Transport, even if they are ClientLogHeaders. This includes the signed
+// level override, which could otherwise be replayed by the readers of the logs until it expires
+var defaultRedactedHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", LevelOverrideHeader}
+
+// Redaction lists the query parameters and headers whose values must not be logged
+type Redaction struct {
+	QueryParams []string
+	Headers     []string
+}
+
+// WithRedaction attaches a redaction to the context, which is applied when logging requests made with that context
+func WithRedaction(ctx context.Context, redaction Redaction) context.Context {
+	return context.WithValue(ctx, redactionKey{}, redaction)
+}
+
+// redactionFromContext retrieves the redaction attached to the context, if any
+func redactionFromContext(ctx context.Context) Redaction {
+	redaction, _ := ctx.Value(redactionKey{}).(Redaction)
+	return redaction
+}
+
+// Transport wraps an http.RoundTripper so that outgoing requests carry the request ID and span of the request context
+// in the formats of Options.InjectFormats, and are logged along with their response once its body has been read or
+// closed. The http.DefaultTransport is used if base is nil
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip sets the request ID header on a copy of the request, since a RoundTripper should not modify the request
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	start := time.Now()
+	cfg := currentConfig()
+
+	ctx, requestID := fromContext(r.Context(), cfg)
+	r2 := r.Clone(ctx)
+	if hasFormat(cfg.injectFormats, RequestIDFormat) {
+		r2.Header.Set(cfg.requestIDHeader, requestID)
+	}
+	if sc, ok := SpanFromContext(ctx); ok {
+		injectSpan(headerCarrier(r2.Header), sc, cfg.injectFormats)
+	}
+	if override, ok := ctx.Value(levelOverrideKey{}).(levelOverride); ok && cfg.forwardsLevelOverride(r2.URL.Host) {
+		r2.Header.Set(LevelOverrideHeader, override.token)
+	}
+
+	resp, err := t.base.RoundTrip(r2)
+
+	redaction := redactionFromContext(ctx)
+	payload := Fields{
+		"Method":       r2.Method,
+		"Host":         r2.URL.Host,
+		"RequestPath":  r2.URL.Path,
+		"RequestQuery": redactQuery(r2.URL.Query(), redaction.QueryParams),
+		"RequestID":    requestID,
+		"ResponseTime": time.Since(start).Seconds(),
+	}
+	if headers := redactHeaders(r2.Header, cfg.requestIDHeader, cfg.clientLogHeaders, redaction.Headers); len(headers) > 0 {
+		payload["RequestHeaders"] = headers
+	}
+
+	if err != nil {
+		withTracing(ctx, cfg, payload, err).log(ctx, cfg, cfg.clientErrorLogLevel, "http client request")
+		return resp, err
+	}
+
+	level := cfg.clientLogLevel
+	if resp.StatusCode >= http.StatusInternalServerError {
+		level = cfg.clientErrorLogLevel
+	}
+	payload["Status"] = resp.StatusCode
+
+	logResponse := func(bytes int64) {
+		payload["ResponseBytes"] = bytes
+		withTracing(ctx, cfg, payload).log(ctx, cfg, level, "http client request")
+	}
+
+	switch resp.Body.(type) {
+	case nil:
+		logResponse(0)
+	case io.Writer:
+		// the body of a protocol switch is the connection, whose size is unknown
+		withTracing(ctx, cfg, payload).log(ctx, cfg, level, "http client request")
+	default:
+		resp.Body = &responseBody{ReadCloser: resp.Body, logResponse: logResponse}
+	}
+
+	return resp, err
+}
+
+// responseBody counts the bytes read from the body of a response, whose declared length is unknown for chunked and
+// compressed responses, and logs the response once the body has been read or closed
+type responseBody struct {
+	io.ReadCloser
+	bytes       int64 // accessed atomically, since Close may be called concurrently with Read
+	once        sync.Once
+	logResponse func(bytes int64)
+}
+
+func (b *responseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(&b.bytes, int64(n))
+	if err == io.EOF {
+		b.finish()
+	}
+
+	return n, err
+}
+
+func (b *responseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+
+	return err
+}
+
+func (b *responseBody) finish() {
+	b.once.Do(func() {
+		b.logResponse(atomic.LoadInt64(&b.bytes))
+	})
+}
+
+// redactQuery encodes the query parameters, replacing the values of the redacted parameters
+func redactQuery(query url.Values, redacted []string) string {
+	for _, param := range redacted {
+		if values, ok := query[param]; ok {
+			for i := range values {
+				values[i] = redactedValue
+			}
+		}
+	}
+
+	return query.Encode()
+}
+
+// redactHeaders flattens the logged headers into a map, replacing the values of the default and redacted headers
+func redactHeaders(header http.Header, requestIDHeader string, logged []string, redacted []string) map[string]string {
+	headers := make(map[string]string, len(logged))
+	for _, key := range logged {
+		key = http.CanonicalHeaderKey(key)
+		if key == http.CanonicalHeaderKey(requestIDHeader) {
+			continue // logged as the RequestID
+		}
+		if values, ok := header[key]; ok {
+			headers[key] = strings.Join(values, ", ")
+		}
+	}
+
+	for _, keys := range [][]string{defaultRedactedHeaders, redacted} {
+		for _, key := range keys {
+			key = http.CanonicalHeaderKey(key)
+			if _, ok := headers[key]; ok {
+				headers[key] = redactedValue
+			}
+		}
+	}
+
+	return headers
+}